@@ -0,0 +1,184 @@
+package ovpnstats
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// undefined is printed by OpenVPN for addresses and names which aren't known yet, e.g. during a client's handshake
+const undefined = "UNDEF"
+
+// AddressKind selects one of the addresses of a ClientInfo
+type AddressKind int
+
+const (
+	// AddressReal is the client's Real Address, the source of its connection
+	AddressReal AddressKind = iota
+	// AddressVirtual is the client's Virtual Address inside the VPN
+	AddressVirtual
+	// AddressVirtualV6 is the client's Virtual IPv6 Address inside the VPN
+	AddressVirtualV6
+)
+
+// splitProtocol splits the transport protocol prefix some OpenVPN versions print before addresses, e.g. "udp4:" or
+// "tcp6-server:", returning it normalized to "udp" or "tcp". proto is empty when there's no such prefix
+func splitProtocol(addr string) (proto string, rest string) {
+	i := strings.IndexByte(addr, ':')
+	if i < 0 {
+		return "", addr
+	}
+	prefix := addr[:i]
+	for _, p := range []string{"udp", "tcp"} {
+		if !strings.HasPrefix(prefix, p) {
+			continue
+		}
+		switch strings.TrimPrefix(prefix, p) {
+		case "", "4", "6", "-server", "4-server", "6-server", "-client", "4-client", "6-client":
+			return p, addr[i+1:]
+		}
+	}
+	return "", addr
+}
+
+// splitAddress splits an address as printed by OpenVPN into its host and port, dropping any protocol prefix.
+// It accepts "ipv4", "ipv4:port", "ipv6" and "[ipv6]:port"; port is 0 when absent.
+// IPv6 hosts keep their zone, if any (e.g. "fe80::1%eth0" from "[fe80::1%eth0]:1194"), see splitZone
+func splitAddress(addr string) (string, int) {
+	_, addr = splitProtocol(addr)
+	if strings.HasPrefix(addr, "[") {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]"), 0
+		}
+		return host, parsePort(port)
+	}
+	// A bare IPv6 address contains several colons but no port
+	if strings.Count(addr, ":") == 1 {
+		if host, port, err := net.SplitHostPort(addr); err == nil {
+			return host, parsePort(port)
+		}
+	}
+	return addr, 0
+}
+
+func parsePort(port string) int {
+	p, err := strconv.Atoi(port)
+	if err != nil || p < 0 || p > 65535 {
+		return 0
+	}
+	return p
+}
+
+// splitZone splits the zone of a scoped IPv6 host, e.g. "fe80::1%eth0", which net.ParseIP doesn't accept.
+// zone is empty when there's none
+func splitZone(host string) (ip string, zone string) {
+	if i := strings.LastIndexByte(host, '%'); i >= 0 && strings.Contains(host[:i], ":") {
+		return host[:i], host[i+1:]
+	}
+	return host, ""
+}
+
+// parseAddressIP returns the IP of an address as printed by OpenVPN, or nil if it does not hold one.
+// The zone of link-local IPv6 addresses is dropped, as net.IP can't hold it.
+// Empty and "UNDEF" addresses are not known yet and give nil too
+func parseAddressIP(addr string) net.IP {
+	if addr == "" || addr == undefined {
+		return nil
+	}
+	host, _ := splitAddress(addr)
+	host, _ = splitZone(host)
+	return net.ParseIP(host)
+}
+
+// Address returns the IP of the client's address of the given kind, without its port.
+// ok is false when that address is empty or doesn't hold an IP
+func (c ClientInfo) Address(kind AddressKind) (ip net.IP, ok bool) {
+	var addr string
+	switch kind {
+	case AddressReal:
+		addr = c.RealAddress
+	case AddressVirtual:
+		addr = c.VirtualAddress
+	case AddressVirtualV6:
+		addr = c.VirtualV6Address
+	default:
+		return nil, false
+	}
+	ip = parseAddressIP(addr)
+	return ip, ip != nil
+}
+
+// RealIP returns the IP of the client's Real Address without its port.
+// It's nil while the client is connecting (see IsConnecting) or when the address can't be parsed
+func (c ClientInfo) RealIP() net.IP {
+	return parseAddressIP(c.RealAddress)
+}
+
+// RealPort returns the source port of the client's Real Address, e.g. 50123 for "203.0.113.5:50123" or
+// "[2001:db8::1]:50123", or 0 when it has none, like a bare IPv6 address or a client still connecting
+func (c ClientInfo) RealPort() int {
+	_, port := splitAddress(c.RealAddress)
+	return port
+}
+
+// RealZone returns the zone of the client's Real Address when it's a scoped IPv6 address, e.g. "eth0" for
+// "[fe80::1%eth0]:1194", or "" otherwise
+func (c ClientInfo) RealZone() string {
+	host, _ := splitAddress(c.RealAddress)
+	_, zone := splitZone(host)
+	return zone
+}
+
+// IsConnecting reports whether the client is still in its handshake, which OpenVPN shows as an empty or "UNDEF" Real Address.
+// These entries are transient and expected during bursts of new connections, not parse errors
+func (c ClientInfo) IsConnecting() bool {
+	return c.RealAddress == "" || c.RealAddress == undefined
+}
+
+// VirtualIP returns the IP of the route's Virtual Address, or nil if it's not a single IPv4 or IPv6 address (e.g. a subnet or a MAC address)
+func (r RoutingInfo) VirtualIP() net.IP {
+	return parseAddressIP(r.VirtualAddress)
+}
+
+// RealIP returns the IP of the route's Real Address without its port, or nil if it can't be parsed
+func (r RoutingInfo) RealIP() net.IP {
+	return parseAddressIP(r.RealAddress)
+}
+
+// maskIP returns the network of `ip` masked to `v4Len` bits for an IPv4 address or to `v6Len` bits for an IPv6 one,
+// in CIDR notation. The length is clamped to the bit length of the IP's family (32 for IPv4, 128 for IPv6)
+func maskIP(ip net.IP, v4Len, v6Len int) string {
+	prefixLen, bits := v6Len, 8*net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, prefixLen, bits = ip4, v4Len, 8*net.IPv4len
+	}
+	if prefixLen < 0 {
+		prefixLen = 0
+	} else if prefixLen > bits {
+		prefixLen = bits
+	}
+	network := net.IPNet{IP: ip.Mask(net.CIDRMask(prefixLen, bits)), Mask: net.CIDRMask(prefixLen, bits)}
+	return network.String()
+}
+
+// RealIPsForUsername returns the distinct IPs, without ports, of the Real Addresses of the clients authenticated as
+// `username`, e.g. from several devices, in client list order. Clients still connecting have no IP and are ignored.
+// "UNDEF", which OpenVPN shows for clients without username, and "" don't identify a user and return nil
+func (s *Status) RealIPsForUsername(username string) []net.IP {
+	if username == "" || username == undefined {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var ips []net.IP
+	for _, client := range s.Clients {
+		if client.Username != username {
+			continue
+		}
+		if ip := client.RealIP(); ip != nil && !seen[ip.String()] {
+			seen[ip.String()] = true
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
@@ -0,0 +1,66 @@
+package ovpnstats_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+func TestClientInfoRealAddressZone(t *testing.T) {
+	tests := []struct {
+		realAddress string
+		wantIP      net.IP
+		wantPort    int
+		wantZone    string
+	}{
+		{realAddress: "[fe80::1%eth0]:1194", wantIP: net.ParseIP("fe80::1"), wantPort: 1194, wantZone: "eth0"},
+		{realAddress: "fe80::1%eth0", wantIP: net.ParseIP("fe80::1"), wantZone: "eth0"},
+		{realAddress: "udp6:[fe80::1%eth0]:1194", wantIP: net.ParseIP("fe80::1"), wantPort: 1194, wantZone: "eth0"},
+		{realAddress: "[fe80::1%25]:1194", wantIP: net.ParseIP("fe80::1"), wantPort: 1194, wantZone: "25"},
+		{realAddress: "[fe80::1%en0.100]:1194", wantIP: net.ParseIP("fe80::1"), wantPort: 1194, wantZone: "en0.100"},
+		{realAddress: "[2001:db8::1]:1194", wantIP: net.ParseIP("2001:db8::1"), wantPort: 1194},
+		{realAddress: "203.0.113.5:1194", wantIP: net.ParseIP("203.0.113.5"), wantPort: 1194},
+	}
+	for _, test := range tests {
+		t.Run(test.realAddress, func(t *testing.T) {
+			client := ovpnstats.ClientInfo{RealAddress: test.realAddress}
+			if ip := client.RealIP(); !ip.Equal(test.wantIP) {
+				t.Errorf("RealIP() = %v, want %v", ip, test.wantIP)
+			}
+			if port := client.RealPort(); port != test.wantPort {
+				t.Errorf("RealPort() = %d, want %d", port, test.wantPort)
+			}
+			if zone := client.RealZone(); zone != test.wantZone {
+				t.Errorf("RealZone() = %q, want %q", zone, test.wantZone)
+			}
+		})
+	}
+}
+
+func TestClientInfoRealPort(t *testing.T) {
+	tests := []struct {
+		realAddress string
+		want        int
+	}{
+		{realAddress: "203.0.113.5:50123", want: 50123},
+		{realAddress: "udp4:203.0.113.5:50123", want: 50123},
+		{realAddress: "tcp4-server:203.0.113.5:443", want: 443},
+		{realAddress: "[2001:db8::1]:50123", want: 50123},
+		{realAddress: "udp6:[2001:db8::1]:1194", want: 1194},
+		{realAddress: "203.0.113.5", want: 0},
+		{realAddress: "2001:db8::1", want: 0},
+		{realAddress: "[2001:db8::1]", want: 0},
+		{realAddress: "203.0.113.5:70000", want: 0},
+		{realAddress: "UNDEF", want: 0},
+		{realAddress: "", want: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.realAddress, func(t *testing.T) {
+			client := ovpnstats.ClientInfo{RealAddress: test.realAddress}
+			if got := client.RealPort(); got != test.want {
+				t.Errorf("RealPort() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
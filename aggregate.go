@@ -0,0 +1,89 @@
+package ovpnstats
+
+import "io"
+
+// StreamHandler receives the records of ParseStatusStream as they're parsed. Nil functions ignore their records,
+// and an error returned by a function stops the parse with it
+type StreamHandler struct {
+	Client func(ClientInfo) error
+	Route  func(RoutingInfo) error
+}
+
+func (h *StreamHandler) client(c ClientInfo) error {
+	if h.Client == nil {
+		return nil
+	}
+	return h.Client(c)
+}
+
+func (h *StreamHandler) route(r RoutingInfo) error {
+	if h.Route == nil {
+		return nil
+	}
+	return h.Route(r)
+}
+
+// ParseStatusStream parses a status file from `r` like ParseStatus, but passes each client and route kept to `handler`
+// in input order instead of retaining them, so memory doesn't grow with the size of the input.
+// The returned Status holds everything else (version, TITLE, TIME and GLOBAL_STATS) but no clients nor routes.
+// WithConcurrency is ignored, as it would read the whole input first
+func ParseStatusStream(r io.Reader, handler StreamHandler, opts ...Option) (*Status, error) {
+	o := newOptions(opts)
+	o.concurrency = 0
+	p := newParser(o)
+	p.stream = &handler
+	if err := p.run(r); err != nil {
+		return nil, err
+	}
+	return p.status, nil
+}
+
+// Aggregate holds the totals of the clients fed to an Aggregator
+type Aggregate struct {
+	Clients int
+	Traffic Traffic
+	// ByCipher is the traffic grouped by normalized Data Channel Cipher, like Status.BytesByCipher
+	ByCipher map[string]Traffic
+}
+
+// Aggregator computes the totals of clients fed one at a time, without retaining them, e.g. from ParseStatusStream:
+//
+//	var a ovpnstats.Aggregator
+//	_, err := ovpnstats.ParseStatusStream(r, a.Handler())
+//
+// The zero value is ready to use. It's not safe for concurrent use
+type Aggregator struct {
+	result Aggregate
+}
+
+// Feed adds `client` to the totals
+func (a *Aggregator) Feed(client ClientInfo) {
+	if a.result.ByCipher == nil {
+		a.result.ByCipher = make(map[string]Traffic)
+	}
+	traffic := Traffic{Rx: client.BytesReceived, Tx: client.BytesSent}
+	cipher := normalizeCipher(client.DataChannelCipher)
+	a.result.Clients++
+	a.result.Traffic = a.result.Traffic.add(traffic)
+	a.result.ByCipher[cipher] = a.result.ByCipher[cipher].add(traffic)
+}
+
+// Handler returns a StreamHandler feeding the clients to `a`
+func (a *Aggregator) Handler() StreamHandler {
+	return StreamHandler{Client: func(client ClientInfo) error {
+		a.Feed(client)
+		return nil
+	}}
+}
+
+// Result returns a copy of the totals of the clients fed so far
+func (a *Aggregator) Result() Aggregate {
+	result := a.result
+	result.ByCipher = copyTraffic(a.result.ByCipher)
+	return result
+}
+
+// Reset clears the totals, e.g. before feeding a new snapshot
+func (a *Aggregator) Reset() {
+	a.result = Aggregate{}
+}
@@ -0,0 +1,78 @@
+package ovpnstats_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+func TestAggregatorStream(t *testing.T) {
+	input := strings.ReplaceAll(statusV2, "HEADER,ROUTING_TABLE", "CLIENT_LIST,carol,198.51.100.7:40000,10.8.0.4,,1000,2000,2021-03-01 09:45:00,1614591900,UNDEF,2,2,aes-256-gcm\nHEADER,ROUTING_TABLE")
+	var a ovpnstats.Aggregator
+	status, err := ovpnstats.ParseStatusStream(strings.NewReader(input), a.Handler())
+	if err != nil {
+		t.Fatalf("ParseStatusStream: %v", err)
+	}
+	if len(status.Clients) != 0 || len(status.Routes) != 0 {
+		t.Errorf("ParseStatusStream kept %d clients and %d routes, want none", len(status.Clients), len(status.Routes))
+	}
+	want := ovpnstats.Aggregate{
+		Clients:  3,
+		Traffic:  ovpnstats.Traffic{Rx: 1400, Tx: 2600},
+		ByCipher: mustParse(t, input).BytesByCipher(),
+	}
+	if got := a.Result(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Result() = %+v, want %+v", got, want)
+	}
+	if got := want.ByCipher["AES-256-GCM"]; got != (ovpnstats.Traffic{Rx: 1100, Tx: 2200}) {
+		t.Errorf("AES-256-GCM traffic = %+v, want both spellings of the cipher together", got)
+	}
+}
+
+func TestAggregator(t *testing.T) {
+	var a ovpnstats.Aggregator
+	if got := a.Result(); got.Clients != 0 || got.Traffic != (ovpnstats.Traffic{}) || len(got.ByCipher) != 0 {
+		t.Errorf("zero Aggregator Result() = %+v, want no totals", got)
+	}
+
+	a.Feed(ovpnstats.ClientInfo{Name: "alice", BytesReceived: 100, BytesSent: 200, DataChannelCipher: "AES-256-GCM"})
+	a.Feed(ovpnstats.ClientInfo{Name: "bob", BytesReceived: 300, BytesSent: 400})
+	result := a.Result()
+	want := ovpnstats.Aggregate{
+		Clients:  2,
+		Traffic:  ovpnstats.Traffic{Rx: 400, Tx: 600},
+		ByCipher: map[string]ovpnstats.Traffic{"AES-256-GCM": {Rx: 100, Tx: 200}, "none": {Rx: 300, Tx: 400}},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Result() = %+v, want %+v", result, want)
+	}
+
+	result.ByCipher["none"] = ovpnstats.Traffic{}
+	a.Feed(ovpnstats.ClientInfo{Name: "carol", BytesReceived: 1, BytesSent: 1})
+	if got := a.Result().ByCipher["none"]; got != (ovpnstats.Traffic{Rx: 301, Tx: 401}) {
+		t.Errorf("none traffic = %+v after changing a previous Result, want it unaffected", got)
+	}
+
+	a.Reset()
+	if got := a.Result(); got.Clients != 0 || got.Traffic != (ovpnstats.Traffic{}) || len(got.ByCipher) != 0 {
+		t.Errorf("Result() = %+v after Reset, want no totals", got)
+	}
+}
+
+func TestParseStatusStreamHandlerError(t *testing.T) {
+	stop := errors.New("stop")
+	var seen []string
+	handler := ovpnstats.StreamHandler{Client: func(client ovpnstats.ClientInfo) error {
+		seen = append(seen, client.Name)
+		return stop
+	}}
+	if _, err := ovpnstats.ParseStatusStream(strings.NewReader(statusV2), handler); !errors.Is(err, stop) {
+		t.Errorf("ParseStatusStream error = %v, want the handler's", err)
+	}
+	if !reflect.DeepEqual(seen, []string{"alice"}) {
+		t.Errorf("handler got %v, want only the first client", seen)
+	}
+}
@@ -0,0 +1,114 @@
+package ovpnstats
+
+// ClientEventType is the kind of change of a ClientEvent
+type ClientEventType int
+
+const (
+	// ClientConnected adds the event's client, replacing the one matching it if any
+	ClientConnected ClientEventType = iota
+	// ClientDisconnected removes the client matching the event's one, and its routes
+	ClientDisconnected
+	// ClientUpdated replaces the client matching the event's one, e.g. with new byte counters,
+	// or adds it when there's none, as its connection was missed
+	ClientUpdated
+)
+
+// ClientEvent is an incremental change of the clients of a Status, e.g. from a management interface notification
+type ClientEvent struct {
+	Type   ClientEventType
+	Client ClientInfo
+}
+
+// Applier maintains a live Status from a base snapshot and the ClientEvent following it, without fetching a full
+// status after each change. An event's client matches a client of the Status with the same Common Name and Client ID,
+// and the same Connected Since when both are known, so events carrying only a Client ID, like disconnections, still
+// match their session. Events are applied in order, so the last event about a session wins.
+// Connections also add a route to the client's Virtual Address, if any, and disconnections remove the routes of the
+// session, correlated like Status.RoutesFor does, unless another session shares them.
+// Applying an event scans the clients, and the routes for disconnections, but copies nothing: the Status is only copied
+// when asked for after a change.
+// It's not safe for concurrent use
+type Applier struct {
+	status *Status
+	// snapshot is the copy of status returned by Status, until the next change
+	snapshot *Status
+}
+
+// NewApplier returns an Applier starting from a copy of `base`, which is never modified. A nil `base` has no clients
+func NewApplier(base *Status) *Applier {
+	status := base.Clone()
+	if status == nil {
+		status = &Status{}
+	}
+	return &Applier{status: status}
+}
+
+// Apply applies `events` in order
+func (a *Applier) Apply(events ...ClientEvent) {
+	if len(events) > 0 {
+		a.snapshot = nil
+	}
+	for _, event := range events {
+		a.apply(event)
+	}
+}
+
+// Status returns a copy of the current Status, which the Applier doesn't modify afterwards. The copy is made once per
+// change: calls without Apply in between return the same Status, which must not be modified
+func (a *Applier) Status() *Status {
+	if a.snapshot == nil {
+		a.snapshot = a.status.Clone()
+	}
+	return a.snapshot
+}
+
+func (a *Applier) apply(event ClientEvent) {
+	i := a.find(event.Client)
+	switch event.Type {
+	case ClientConnected, ClientUpdated:
+		client := event.Client.clone()
+		if i >= 0 {
+			a.status.Clients[i] = client
+			return
+		}
+		a.status.Clients = append(a.status.Clients, client)
+		if event.Type == ClientConnected && client.VirtualAddress != "" {
+			a.status.Routes = append(a.status.Routes, RoutingInfo{
+				VirtualAddress: client.VirtualAddress,
+				CommonName:     client.Name,
+				RealAddress:    client.RealAddress,
+				LastRef:        client.ConnectedSince,
+			})
+		}
+	case ClientDisconnected:
+		if i < 0 {
+			return
+		}
+		client := a.status.Clients[i]
+		a.status.Clients = append(a.status.Clients[:i], a.status.Clients[i+1:]...)
+		if len(a.status.ClientsFor(RoutingInfo{CommonName: client.Name, RealAddress: client.RealAddress})) > 0 {
+			return
+		}
+		routes := a.status.Routes[:0]
+		for _, route := range a.status.Routes {
+			if !sameEndpoint(route, client) {
+				routes = append(routes, route)
+			}
+		}
+		a.status.Routes = routes
+	}
+}
+
+// find returns the index of the client matching `client`, or -1
+func (a *Applier) find(client ClientInfo) int {
+	for i, candidate := range a.status.Clients {
+		if candidate.Name != client.Name || candidate.ClientID != client.ClientID {
+			continue
+		}
+		if candidate.HasConnectedSince() && client.HasConnectedSince() && !candidate.ConnectedSince.Equal(client.ConnectedSince) {
+			continue
+		}
+		return i
+	}
+	return -1
+}
@@ -0,0 +1,47 @@
+package ovpnstats_test
+
+import (
+	"testing"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+func TestApplier(t *testing.T) {
+	base := mustParse(t, statusV2)
+	alice, bob := base.Clients[0], base.Clients[1]
+	carol := ovpnstats.ClientInfo{Name: "carol", RealAddress: "192.0.2.9:1194", VirtualAddress: "10.8.0.4", ClientID: 2}
+	updated := alice
+	updated.BytesReceived = 1000
+
+	applier := ovpnstats.NewApplier(base)
+	before := applier.Status()
+	if applier.Status() != before {
+		t.Errorf("Status() copied the unchanged Status again")
+	}
+	applier.Apply(
+		ovpnstats.ClientEvent{Type: ovpnstats.ClientConnected, Client: carol},
+		ovpnstats.ClientEvent{Type: ovpnstats.ClientUpdated, Client: updated},
+		ovpnstats.ClientEvent{Type: ovpnstats.ClientDisconnected, Client: ovpnstats.ClientInfo{Name: bob.Name, ClientID: bob.ClientID}},
+	)
+	status := applier.Status()
+
+	var names []string
+	for _, client := range status.Clients {
+		names = append(names, client.Name)
+	}
+	if len(names) != 2 || names[0] != "alice" || names[1] != "carol" {
+		t.Fatalf("got clients %v, want alice and carol", names)
+	}
+	if status.Clients[0].BytesReceived != 1000 {
+		t.Errorf("alice's Bytes Received = %d, want 1000", status.Clients[0].BytesReceived)
+	}
+	if routes := status.RoutesFor(carol); len(routes) != 1 || routes[0].VirtualAddress != "10.8.0.4" {
+		t.Errorf("carol's routes = %+v, want one to 10.8.0.4", routes)
+	}
+	if routes := status.RoutesFor(bob); len(routes) != 0 {
+		t.Errorf("bob's routes = %+v, want none", routes)
+	}
+	if len(before.Clients) != 2 || before.Clients[1].Name != "bob" || len(base.Clients) != 2 {
+		t.Errorf("Apply modified an earlier Status or the base one")
+	}
+}
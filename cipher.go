@@ -0,0 +1,45 @@
+package ovpnstats
+
+import (
+	"sort"
+	"strings"
+)
+
+// noCipher is the key of clients without a Data Channel Cipher
+const noCipher = "none"
+
+// normalizeCipher returns the canonical upper case name of `cipher`, or "none" when it's empty
+func normalizeCipher(cipher string) string {
+	cipher = strings.ToUpper(strings.TrimSpace(cipher))
+	if cipher == "" || cipher == "NONE" {
+		return noCipher
+	}
+	return cipher
+}
+
+// BytesByCipher returns the traffic of the clients in `s` grouped by their normalized Data Channel Cipher.
+// Clients without cipher are grouped under "none"
+func (s *Status) BytesByCipher() map[string]Traffic {
+	traffic := make(map[string]Traffic)
+	for _, client := range s.Clients {
+		cipher := normalizeCipher(client.DataChannelCipher)
+		traffic[cipher] = traffic[cipher].add(Traffic{Rx: client.BytesReceived, Tx: client.BytesSent})
+	}
+	return traffic
+}
+
+// Ciphers returns the sorted set of normalized Data Channel Ciphers of the clients in `s`, including "none" when
+// some client has no cipher
+func (s *Status) Ciphers() []string {
+	seen := make(map[string]bool)
+	var ciphers []string
+	for _, client := range s.Clients {
+		cipher := normalizeCipher(client.DataChannelCipher)
+		if !seen[cipher] {
+			seen[cipher] = true
+			ciphers = append(ciphers, cipher)
+		}
+	}
+	sort.Strings(ciphers)
+	return ciphers
+}
@@ -0,0 +1,60 @@
+package ovpnstats
+
+import "time"
+
+// ClientColumns holds the clients of a Status as parallel slices, one per ClientInfo field, for columnar exports.
+// All the slices have one element per client, in client list order, so the i-th element of every slice belongs to
+// the i-th client. Extra columns aren't included
+type ClientColumns struct {
+	Names              []string
+	RealAddresses      []string
+	VirtualAddresses   []string
+	VirtualV6Addresses []string
+	BytesReceived      []int64
+	BytesSent          []int64
+	ConnectedSince     []time.Time
+	Usernames          []string
+	ClientIDs          []int
+	PeerIDs            []int
+	DataChannelCiphers []string
+	Protocols          []string
+}
+
+// Columns returns the clients of `s` as ClientColumns
+func (s *Status) Columns() ClientColumns {
+	n := len(s.Clients)
+	columns := ClientColumns{
+		Names:              make([]string, n),
+		RealAddresses:      make([]string, n),
+		VirtualAddresses:   make([]string, n),
+		VirtualV6Addresses: make([]string, n),
+		BytesReceived:      make([]int64, n),
+		BytesSent:          make([]int64, n),
+		ConnectedSince:     make([]time.Time, n),
+		Usernames:          make([]string, n),
+		ClientIDs:          make([]int, n),
+		PeerIDs:            make([]int, n),
+		DataChannelCiphers: make([]string, n),
+		Protocols:          make([]string, n),
+	}
+	for i, client := range s.Clients {
+		columns.Names[i] = client.Name
+		columns.RealAddresses[i] = client.RealAddress
+		columns.VirtualAddresses[i] = client.VirtualAddress
+		columns.VirtualV6Addresses[i] = client.VirtualV6Address
+		columns.BytesReceived[i] = client.BytesReceived
+		columns.BytesSent[i] = client.BytesSent
+		columns.ConnectedSince[i] = client.ConnectedSince
+		columns.Usernames[i] = client.Username
+		columns.ClientIDs[i] = client.ClientID
+		columns.PeerIDs[i] = client.PeerID
+		columns.DataChannelCiphers[i] = client.DataChannelCipher
+		columns.Protocols[i] = client.Protocol
+	}
+	return columns
+}
+
+// Len returns the number of clients in the columns
+func (c ClientColumns) Len() int {
+	return len(c.Names)
+}
@@ -0,0 +1,69 @@
+package ovpnstats
+
+import "sync"
+
+// minConcurrentRecords is the number of records below which parsing them concurrently isn't worth it
+const minConcurrentRecords = 1024
+
+// parseConcurrently parses every line from `lines` up to END, deferring the records to `workers` concurrent workers
+func (p *parser) parseConcurrently(lines lineScanner, workers int) error {
+	var records []*record
+	p.deferred = &records
+	// An error stops reading, but the records before it must be checked first to report the same error as a sequential parse
+	var lineErr error
+	for lines.Scan() {
+		if lineErr = p.parseLine(lines.Text()); lineErr != nil || p.ended {
+			break
+		}
+	}
+	p.deferred = nil
+
+	if len(records) < minConcurrentRecords {
+		workers = 1
+	}
+	chunk := (len(records) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for start := 0; start < len(records); start += chunk {
+		end := start + chunk
+		if end > len(records) {
+			end = len(records)
+		}
+		wg.Add(1)
+		go func(records []*record) {
+			defer wg.Done()
+			for _, r := range records {
+				r.parse()
+			}
+		}(records[start:end])
+	}
+	wg.Wait()
+
+	p.grow(records)
+	for _, r := range records {
+		if err := p.add(r); err != nil {
+			return err
+		}
+	}
+	return lineErr
+}
+
+// grow makes room in p.status for `records`, so that adding them doesn't grow the sections one record at a time
+func (p *parser) grow(records []*record) {
+	if p.stream != nil {
+		return
+	}
+	var clients, routes int
+	for _, r := range records {
+		if r.recordType == "CLIENT_LIST" {
+			clients++
+		} else {
+			routes++
+		}
+	}
+	if n := len(p.status.Clients) + clients; n > cap(p.status.Clients) {
+		p.status.Clients = append(make([]ClientInfo, 0, n), p.status.Clients...)
+	}
+	if n := len(p.status.Routes) + routes; n > cap(p.status.Routes) {
+		p.status.Routes = append(make([]RoutingInfo, 0, n), p.status.Routes...)
+	}
+}
@@ -0,0 +1,44 @@
+package ovpnstats_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+func TestParseStatusConcurrentMatchesSequential(t *testing.T) {
+	large := statusFile(5000)
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{name: "large", input: large},
+		{name: "small", input: statusFile(10)},
+		{name: "invalid record", input: bytes.Replace(large, []byte(",1000,2000,"), []byte(",1000,x,"), 1)},
+		{name: "invalid line", input: bytes.Replace(large, []byte("GLOBAL_STATS,Max bcast/mcast queue length,0"), []byte("TIME,x"), 1)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			want, wantErr := ovpnstats.ParseStatus(bytes.NewReader(test.input))
+			got, err := ovpnstats.ParseStatus(bytes.NewReader(test.input), ovpnstats.WithConcurrency(4))
+			if (err == nil) != (wantErr == nil) || (err != nil && err.Error() != wantErr.Error()) {
+				t.Fatalf("concurrent parse error = %v, want %v", err, wantErr)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("concurrent parse differs from the sequential one")
+			}
+		})
+	}
+}
+
+func TestParseStatusConcurrentLenient(t *testing.T) {
+	input := strings.Replace(string(statusFile(2000)), ",1000,2000,", ",1000,x,", 1)
+	want := mustParse(t, input, ovpnstats.WithLenient())
+	got := mustParse(t, input, ovpnstats.WithLenient(), ovpnstats.WithConcurrency(4))
+	if len(want.Warnings) != 1 || !reflect.DeepEqual(got, want) {
+		t.Errorf("concurrent lenient parse gave %d warnings, want the same %d as the sequential one", len(got.Warnings), len(want.Warnings))
+	}
+}
@@ -0,0 +1,114 @@
+package ovpnstats
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// SessionChange is a session present in two snapshots
+type SessionChange struct {
+	Previous ClientInfo
+	Current  ClientInfo
+}
+
+// Traffic returns the bytes transferred by the session between both snapshots
+func (c SessionChange) Traffic() Traffic {
+	return Traffic{
+		Rx: counterDelta(c.Previous.BytesReceived, c.Current.BytesReceived),
+		Tx: counterDelta(c.Previous.BytesSent, c.Current.BytesSent),
+	}
+}
+
+// RouteChange is a Virtual Address routed in two snapshots to a different Common Name or Real Address
+type RouteChange struct {
+	Previous RoutingInfo
+	Current  RoutingInfo
+}
+
+// DiffResult describes the changes of the clients and routes from a snapshot to a later one.
+// Sessions are matched by Common Name, Client ID and Connected Since, so a reconnection is both a disconnection and a connection.
+// Routes are matched by Virtual Address
+type DiffResult struct {
+	// Connected are the sessions of the later snapshot missing from the earlier one
+	Connected []ClientInfo
+	// Disconnected are the sessions of the earlier snapshot missing from the later one
+	Disconnected []ClientInfo
+	// Remaining are the sessions of both snapshots
+	Remaining []SessionChange
+	// AddedRoutes are the routes of the later snapshot whose Virtual Address isn't routed in the earlier one
+	AddedRoutes []RoutingInfo
+	// RemovedRoutes are the routes of the earlier snapshot whose Virtual Address isn't routed in the later one
+	RemovedRoutes []RoutingInfo
+	// ChangedRoutes are the Virtual Addresses routed in both snapshots to a different Common Name or Real Address.
+	// A Last Ref change alone isn't one, as it's refreshed by any traffic
+	ChangedRoutes []RouteChange
+}
+
+// Diff compares the clients and routes of `prev` with those of the later snapshot `curr`. A nil snapshot has neither
+func Diff(prev, curr *Status) *DiffResult {
+	previous := make(map[sessionKey]ClientInfo, prev.NumClients())
+	for _, client := range prev.clients() {
+		previous[client.session()] = client
+	}
+
+	result := &DiffResult{}
+	seen := make(map[sessionKey]bool, curr.NumClients())
+	for _, client := range curr.clients() {
+		key := client.session()
+		seen[key] = true
+		if old, ok := previous[key]; ok {
+			result.Remaining = append(result.Remaining, SessionChange{Previous: old, Current: client})
+		} else {
+			result.Connected = append(result.Connected, client)
+		}
+	}
+	for _, client := range prev.clients() {
+		if !seen[client.session()] {
+			result.Disconnected = append(result.Disconnected, client)
+		}
+	}
+	diffRoutes(result, prev.routes(), curr.routes())
+	return result
+}
+
+// diffRoutes adds the changes from the routes `prev` to the later `curr` to `result`
+func diffRoutes(result *DiffResult, prev, curr []RoutingInfo) {
+	previous := make(map[string]RoutingInfo, len(prev))
+	for _, route := range prev {
+		previous[route.VirtualAddress] = route
+	}
+	routed := make(map[string]bool, len(curr))
+	for _, route := range curr {
+		routed[route.VirtualAddress] = true
+		old, ok := previous[route.VirtualAddress]
+		switch {
+		case !ok:
+			result.AddedRoutes = append(result.AddedRoutes, route)
+		case old.CommonName != route.CommonName || old.RealAddress != route.RealAddress:
+			result.ChangedRoutes = append(result.ChangedRoutes, RouteChange{Previous: old, Current: route})
+		}
+	}
+	for _, route := range prev {
+		if !routed[route.VirtualAddress] {
+			result.RemovedRoutes = append(result.RemovedRoutes, route)
+		}
+	}
+}
+
+// DiffFiles parses the status files at `prevPath` and `currPath` with `opts` and compares them with Diff.
+// A missing `prevPath`, e.g. on a first run, is an empty snapshot, so every client of `currPath` is Connected
+// and every route is added
+func DiffFiles(prevPath, currPath string, opts ...Option) (*DiffResult, error) {
+	prev, err := parseStatusFile(prevPath, opts)
+	if errors.Is(err, fs.ErrNotExist) {
+		prev, err = &Status{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	curr, err := parseStatusFile(currPath, opts)
+	if err != nil {
+		return nil, err
+	}
+	return Diff(prev, curr), nil
+}
@@ -0,0 +1,208 @@
+package ovpnstats_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+// names returns the Common Names of `clients`, in order
+func names(clients []ovpnstats.ClientInfo) []string {
+	var names []string
+	for _, client := range clients {
+		names = append(names, client.Name)
+	}
+	return names
+}
+
+// virtualAddresses returns the Virtual Addresses of `routes`, in order
+func virtualAddresses(routes []ovpnstats.RoutingInfo) []string {
+	var addresses []string
+	for _, route := range routes {
+		addresses = append(addresses, route.VirtualAddress)
+	}
+	return addresses
+}
+
+func TestDiff(t *testing.T) {
+	alice := session("alice", 0, 0, 1614589200)
+	bob := session("bob", 1, 1, 1614591000)
+	carol := session("carol", 2, 2, 1614591600)
+	aliceTraffic := alice
+	aliceTraffic.BytesReceived, aliceTraffic.BytesSent, aliceTraffic.RealAddress = 100, 200, "198.51.100.7:40000"
+	aliceNewClientID := alice
+	aliceNewClientID.ClientID = 3
+	aliceReconnected := alice
+	aliceReconnected.ConnectedSince = time.Unix(1614592800, 0)
+	aliceRoute := ovpnstats.RoutingInfo{VirtualAddress: "10.8.0.2", CommonName: "alice", RealAddress: "203.0.113.5:1194", LastRef: time.Unix(1614592740, 0)}
+	aliceRouteRefreshed := aliceRoute
+	aliceRouteRefreshed.LastRef = time.Unix(1614592800, 0)
+	aliceRouteRoamed := aliceRoute
+	aliceRouteRoamed.RealAddress = "198.51.100.7:40000"
+	bobRoute := ovpnstats.RoutingInfo{VirtualAddress: "10.8.0.3", CommonName: "bob", RealAddress: "[2001:db8::1]:50123"}
+	bobRouteToCarol := bobRoute
+	bobRouteToCarol.CommonName = "carol"
+
+	tests := []struct {
+		name             string
+		prev, curr       *ovpnstats.Status
+		wantConnected    []string
+		wantDisconnected []string
+		wantRemaining    []string
+		wantAdded        []string
+		wantRemoved      []string
+		wantChanged      []string
+	}{
+		{
+			name:          "nil previous snapshot",
+			curr:          &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{alice}, Routes: []ovpnstats.RoutingInfo{aliceRoute}},
+			wantConnected: []string{"alice"},
+			wantAdded:     []string{"10.8.0.2"},
+		},
+		{
+			name:             "nil current snapshot",
+			prev:             &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{alice}, Routes: []ovpnstats.RoutingInfo{aliceRoute}},
+			wantDisconnected: []string{"alice"},
+			wantRemoved:      []string{"10.8.0.2"},
+		},
+		{
+			name:             "connected and disconnected",
+			prev:             &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{alice, bob}, Routes: []ovpnstats.RoutingInfo{aliceRoute, bobRoute}},
+			curr:             &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{carol, alice}, Routes: []ovpnstats.RoutingInfo{aliceRoute}},
+			wantConnected:    []string{"carol"},
+			wantDisconnected: []string{"bob"},
+			wantRemaining:    []string{"alice"},
+			wantRemoved:      []string{"10.8.0.3"},
+		},
+		{
+			name:          "changed counters and address keep the session",
+			prev:          &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{alice}, Routes: []ovpnstats.RoutingInfo{aliceRoute}},
+			curr:          &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{aliceTraffic}, Routes: []ovpnstats.RoutingInfo{aliceRouteRoamed}},
+			wantRemaining: []string{"alice"},
+			wantChanged:   []string{"10.8.0.2"},
+		},
+		{
+			name:             "new Client ID is a new session",
+			prev:             &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{alice}},
+			curr:             &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{aliceNewClientID}},
+			wantConnected:    []string{"alice"},
+			wantDisconnected: []string{"alice"},
+		},
+		{
+			name:             "new Connected Since is a new session",
+			prev:             &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{alice}},
+			curr:             &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{aliceReconnected}},
+			wantConnected:    []string{"alice"},
+			wantDisconnected: []string{"alice"},
+		},
+		{
+			name:          "Peer ID change keeps the session",
+			prev:          &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{bob}},
+			curr:          &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{session("bob", 1, 7, 1614591000)}},
+			wantRemaining: []string{"bob"},
+		},
+		{
+			name: "refreshed Last Ref isn't a change",
+			prev: &ovpnstats.Status{Routes: []ovpnstats.RoutingInfo{aliceRoute}},
+			curr: &ovpnstats.Status{Routes: []ovpnstats.RoutingInfo{aliceRouteRefreshed}},
+		},
+		{
+			name:        "route moved to another client",
+			prev:        &ovpnstats.Status{Routes: []ovpnstats.RoutingInfo{aliceRoute, bobRoute}},
+			curr:        &ovpnstats.Status{Routes: []ovpnstats.RoutingInfo{bobRouteToCarol, aliceRoute}},
+			wantChanged: []string{"10.8.0.3"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diff := ovpnstats.Diff(test.prev, test.curr)
+			var remaining, changed []string
+			for _, change := range diff.Remaining {
+				if change.Previous.Name != change.Current.Name {
+					t.Errorf("Remaining matched %q with %q", change.Previous.Name, change.Current.Name)
+				}
+				remaining = append(remaining, change.Current.Name)
+			}
+			for _, change := range diff.ChangedRoutes {
+				if change.Previous.VirtualAddress != change.Current.VirtualAddress {
+					t.Errorf("ChangedRoutes matched %q with %q", change.Previous.VirtualAddress, change.Current.VirtualAddress)
+				}
+				changed = append(changed, change.Current.VirtualAddress)
+			}
+			for _, check := range []struct {
+				field     string
+				got, want []string
+			}{
+				{"Connected", names(diff.Connected), test.wantConnected},
+				{"Disconnected", names(diff.Disconnected), test.wantDisconnected},
+				{"Remaining", remaining, test.wantRemaining},
+				{"AddedRoutes", virtualAddresses(diff.AddedRoutes), test.wantAdded},
+				{"RemovedRoutes", virtualAddresses(diff.RemovedRoutes), test.wantRemoved},
+				{"ChangedRoutes", changed, test.wantChanged},
+			} {
+				if !reflect.DeepEqual(check.got, check.want) {
+					t.Errorf("%s = %v, want %v", check.field, check.got, check.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSessionChangeTraffic(t *testing.T) {
+	tests := []struct {
+		name                           string
+		prevRx, prevTx, currRx, currTx int64
+		want                           ovpnstats.Traffic
+	}{
+		{name: "growth", prevRx: 100, prevTx: 200, currRx: 150, currTx: 260, want: ovpnstats.Traffic{Rx: 50, Tx: 60}},
+		{name: "unchanged", prevRx: 100, prevTx: 200, currRx: 100, currTx: 200},
+		{name: "counter reset", prevRx: 100, prevTx: 200, currRx: 30, currTx: 40, want: ovpnstats.Traffic{Rx: 30, Tx: 40}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			change := ovpnstats.SessionChange{
+				Previous: ovpnstats.ClientInfo{BytesReceived: test.prevRx, BytesSent: test.prevTx},
+				Current:  ovpnstats.ClientInfo{BytesReceived: test.currRx, BytesSent: test.currTx},
+			}
+			if got := change.Traffic(); got != test.want {
+				t.Errorf("Traffic() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDiffFiles(t *testing.T) {
+	dir := t.TempDir()
+	prevPath := filepath.Join(dir, "previous.log")
+	currPath := filepath.Join(dir, "current.log")
+	if err := os.WriteFile(currPath, []byte(statusV2), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := ovpnstats.DiffFiles(prevPath, currPath)
+	if err != nil {
+		t.Fatalf("DiffFiles with a missing previous file: %v", err)
+	}
+	if got := names(diff.Connected); !reflect.DeepEqual(got, []string{"alice", "bob"}) || len(diff.AddedRoutes) != 2 {
+		t.Errorf("missing previous file gave Connected %v and AddedRoutes %v, want every client and route", got, diff.AddedRoutes)
+	}
+
+	if err := os.WriteFile(prevPath, []byte(statusV2), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	diff, err = ovpnstats.DiffFiles(prevPath, currPath)
+	if err != nil {
+		t.Fatalf("DiffFiles: %v", err)
+	}
+	if len(diff.Connected)+len(diff.Disconnected)+len(diff.AddedRoutes)+len(diff.RemovedRoutes)+len(diff.ChangedRoutes) != 0 || len(diff.Remaining) != 2 {
+		t.Errorf("same file gave %+v, want every session remaining", diff)
+	}
+
+	if _, err := ovpnstats.DiffFiles(prevPath, filepath.Join(dir, "missing.log")); !os.IsNotExist(err) {
+		t.Errorf("DiffFiles with a missing current file error = %v, want a not exist error", err)
+	}
+}
@@ -0,0 +1,43 @@
+package ovpnstats
+
+// Equal reports whether `c` and `other` hold the same values. Connected Since is compared with time.Time.Equal,
+// ignoring its location and monotonic clock reading, and nil Extra and Metadata equal empty ones
+func (c ClientInfo) Equal(other ClientInfo) bool {
+	if c.Name != other.Name ||
+		c.RealAddress != other.RealAddress ||
+		c.VirtualAddress != other.VirtualAddress ||
+		c.VirtualV6Address != other.VirtualV6Address ||
+		c.BytesReceived != other.BytesReceived ||
+		c.BytesSent != other.BytesSent ||
+		!c.ConnectedSince.Equal(other.ConnectedSince) ||
+		c.Username != other.Username ||
+		c.ClientID != other.ClientID ||
+		c.PeerID != other.PeerID ||
+		c.DataChannelCipher != other.DataChannelCipher ||
+		c.Protocol != other.Protocol {
+		return false
+	}
+	return equalStrings(c.Extra, other.Extra) && equalStrings(c.Metadata, other.Metadata)
+}
+
+// equalStrings reports whether `a` and `b` hold the same entries
+func equalStrings(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if other, ok := b[k]; !ok || other != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal reports whether `r` and `other` hold the same values. Last Ref is compared with time.Time.Equal,
+// ignoring its location and monotonic clock reading
+func (r RoutingInfo) Equal(other RoutingInfo) bool {
+	return r.VirtualAddress == other.VirtualAddress &&
+		r.CommonName == other.CommonName &&
+		r.RealAddress == other.RealAddress &&
+		r.LastRef.Equal(other.LastRef)
+}
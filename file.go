@@ -0,0 +1,99 @@
+package ovpnstats
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ErrSymlink is returned by ParseStatusFile with WithNoSymlinks when the status file is a symbolic link
+var ErrSymlink = errors.New("ovpnstats: status file is a symbolic link")
+
+// openFile opens a file for reading, replaced by tests to change the file system between the steps of openStatusFile
+var openFile = os.Open
+
+// openStatusFile opens the status file at `filename`, enforcing WithNoSymlinks
+func openStatusFile(filename string, o *options) (*os.File, error) {
+	if !o.noSymlinks {
+		return openFile(filename)
+	}
+	info, err := os.Lstat(filename)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return nil, fmt.Errorf("%w: %s", ErrSymlink, filename)
+	}
+	file, err := openFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	// The path may have been swapped for a symlink between Lstat and Open
+	opened, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if !os.SameFile(info, opened) {
+		file.Close()
+		return nil, fmt.Errorf("%w: %s was replaced while being opened", ErrSymlink, filename)
+	}
+	return file, nil
+}
+
+// atomicReadAttempts is the number of times WithAtomicRead reads a status file lacking its END line
+const atomicReadAttempts = 3
+
+// atomicReadRetryDelay is the time WithAtomicRead waits before reading a status file lacking its END line again
+const atomicReadRetryDelay = 20 * time.Millisecond
+
+// parseStatusFile parses the status file at `filename` into a Status
+func parseStatusFile(filename string, opts []Option) (*Status, error) {
+	o := newOptions(opts)
+	if o.atomicRead {
+		content, err := readStatusFile(filename, o)
+		if err != nil {
+			return nil, err
+		}
+		return ParseStatus(bytes.NewReader(content), opts...)
+	}
+	file, err := openStatusFile(filename, o)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseStatus(file, opts...)
+}
+
+// readStatusFile reads the whole status file at `filename`, reading it again while it lacks its END line
+func readStatusFile(filename string, o *options) ([]byte, error) {
+	var content []byte
+	for attempt := 0; attempt < atomicReadAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(atomicReadRetryDelay)
+		}
+		file, err := openStatusFile(filename, o)
+		if err != nil {
+			return nil, err
+		}
+		content, err = io.ReadAll(o.input(file))
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		if hasEndLine(content) {
+			break
+		}
+		o.logger.Printf("ovpnstats: %s has no END line, it may be being rewritten", filename)
+	}
+	return content, nil
+}
+
+// hasEndLine reports whether the last line of `content` is END
+func hasEndLine(content []byte) bool {
+	content = bytes.TrimRight(content, "\r\n")
+	return bytes.Equal(content, []byte("END")) || bytes.HasSuffix(content, []byte("\nEND"))
+}
@@ -0,0 +1,62 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+// +build linux darwin freebsd netbsd openbsd
+
+package ovpnstats_test
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+func TestParseStatusFileFIFO(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "openvpn-status.log")
+	if err := syscall.Mkfifo(path, 0o600); err != nil {
+		t.Skipf("Mkfifo: %v", err)
+	}
+
+	// The writer keeps the pipe open after END, until the test ends
+	written := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		fifo, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			written <- err
+			return
+		}
+		defer fifo.Close()
+		_, err = fifo.WriteString(statusV2)
+		written <- err
+		<-done
+	}()
+
+	type result struct {
+		clients []ovpnstats.ClientInfo
+		err     error
+	}
+	parsed := make(chan result, 1)
+	go func() {
+		clients, _, err := ovpnstats.ParseStatusFile(path, ovpnstats.WithStrictEnd())
+		parsed <- result{clients: clients, err: err}
+	}()
+
+	select {
+	case r := <-parsed:
+		if r.err != nil {
+			t.Fatalf("ParseStatusFile: %v", r.err)
+		}
+		if len(r.clients) != 2 {
+			t.Errorf("got %d clients, want 2", len(r.clients))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ParseStatusFile didn't return after END while the writer kept the FIFO open")
+	}
+	if err := <-written; err != nil {
+		t.Fatalf("writing the FIFO: %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package ovpnstats
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// replaceOpenFile makes openStatusFile call `open` instead of os.Open until the test ends
+func replaceOpenFile(t *testing.T, open func(string) (*os.File, error)) {
+	t.Cleanup(func() { openFile = os.Open })
+	openFile = open
+}
+
+// writeFile writes `content` to `path`, failing `t` on error
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseStatusFileNoSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "other.log")
+	writeFile(t, target, managementStatus)
+	link := filepath.Join(dir, "openvpn-status.log")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("Symlink: %v", err)
+	}
+
+	if _, _, err := ParseStatusFile(link, WithNoSymlinks()); !errors.Is(err, ErrSymlink) {
+		t.Errorf("ParseStatusFile(symlink) error = %v, want ErrSymlink", err)
+	}
+	if _, _, err := ParseStatusFile(target, WithNoSymlinks()); err != nil {
+		t.Errorf("ParseStatusFile(regular file): %v", err)
+	}
+	if clients, _, err := ParseStatusFile(link); err != nil || len(clients) != 1 {
+		t.Errorf("ParseStatusFile(symlink) without WithNoSymlinks = %v, %v, want the target's clients", clients, err)
+	}
+}
+
+func TestParseStatusFileNoSymlinksSwapped(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "other.log")
+	writeFile(t, target, managementStatus)
+	path := filepath.Join(dir, "openvpn-status.log")
+	writeFile(t, path, managementStatus)
+
+	// Swap the path for a symlink after it's been checked with Lstat, right before it's opened
+	replaceOpenFile(t, func(name string) (*os.File, error) {
+		if err := os.Remove(name); err != nil {
+			return nil, err
+		}
+		if err := os.Symlink(target, name); err != nil {
+			t.Skipf("Symlink: %v", err)
+		}
+		return os.Open(name)
+	})
+	if _, _, err := ParseStatusFile(path, WithNoSymlinks()); !errors.Is(err, ErrSymlink) {
+		t.Errorf("ParseStatusFile error = %v, want ErrSymlink", err)
+	}
+}
+
+// logLines is a Logger keeping the lines it's given
+type logLines []string
+
+func (l *logLines) Printf(format string, v ...interface{}) {
+	*l = append(*l, fmt.Sprintf(format, v...))
+}
+
+func TestParseStatusFileAtomicRead(t *testing.T) {
+	// truncated is managementStatus cut in the middle of a line, as read while OpenVPN rewrites it
+	truncated := managementStatus[:strings.Index(managementStatus, "HEADER,ROUTING_TABLE")+10]
+	tests := []struct {
+		name      string
+		completed int // the number of opens after which the file is complete, never when 0
+		wantOpens int
+		wantLogs  int
+		wantErr   error
+	}{
+		{name: "complete", completed: 1, wantOpens: 1},
+		{name: "completed during the retries", completed: 2, wantOpens: 2, wantLogs: 1},
+		{name: "completed on the last retry", completed: atomicReadAttempts, wantOpens: atomicReadAttempts, wantLogs: atomicReadAttempts - 1},
+		{name: "retries run out", wantOpens: atomicReadAttempts, wantLogs: atomicReadAttempts, wantErr: ErrMissingEnd},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "openvpn-status.log")
+			writeFile(t, path, truncated)
+			opens := 0
+			replaceOpenFile(t, func(name string) (*os.File, error) {
+				opens++
+				if opens == test.completed {
+					// OpenVPN finished rewriting the file
+					writeFile(t, name, managementStatus)
+				}
+				return os.Open(name)
+			})
+
+			var logger logLines
+			clients, routes, err := ParseStatusFile(path, WithAtomicRead(), WithStrictEnd(), WithLogger(&logger))
+			if opens != test.wantOpens {
+				t.Errorf("opened the file %d times, want %d", opens, test.wantOpens)
+			}
+			if len(logger) != test.wantLogs {
+				t.Errorf("logged %q, want %d lines", logger, test.wantLogs)
+			}
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("ParseStatusFile error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseStatusFile: %v", err)
+			}
+			if len(clients) != 1 || len(routes) != 1 {
+				t.Errorf("got clients %+v and routes %+v, want alice and her route", clients, routes)
+			}
+		})
+	}
+}
+
+func TestHasEndLine(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{content: "END", want: true},
+		{content: "END\n", want: true},
+		{content: "TITLE,x\r\nEND\r\n\n", want: true},
+		{content: "TITLE,x\nEND\n", want: true},
+		{content: ""},
+		{content: "TITLE,x\n"},
+		{content: "TITLE,x\nEN"},
+		{content: "TITLE,x\nEND\nCLIENT_LIST,alice\n"},
+		{content: "TITLE,xEND\n"},
+	}
+	for _, test := range tests {
+		if got := hasEndLine([]byte(test.content)); got != test.want {
+			t.Errorf("hasEndLine(%q) = %v, want %v", test.content, got, test.want)
+		}
+	}
+}
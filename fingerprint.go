@@ -0,0 +1,67 @@
+package ovpnstats
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FingerprintOptions configures Status.Fingerprint
+type FingerprintOptions struct {
+	// Counters includes the clients' Bytes Received and Bytes Sent, so that any traffic changes the fingerprint
+	Counters bool
+}
+
+// Fingerprint returns a hex encoded SHA-256 digest of the identity of the sessions and routes of `s`, which changes
+// only when a client connects, disconnects or roams, or when a route is added, removed or moved.
+// It covers, for each client, its Common Name, Real Address, Virtual Address, Virtual IPv6 Address, Username,
+// Client ID, Peer ID and Connected Since, plus Bytes Received and Bytes Sent when opts.Counters is set;
+// and for each route, its Virtual Address, Common Name and Real Address.
+// Everything else (Title, UpdatedAt, GLOBAL_STATS, Last Ref, Data Channel Cipher, Extra columns) is ignored,
+// as is the order of the entries
+func (s *Status) Fingerprint(opts FingerprintOptions) string {
+	entries := make([]string, 0, s.NumClients()+s.NumRoutes())
+	if s != nil {
+		for _, client := range s.Clients {
+			fields := []string{
+				"CLIENT_LIST",
+				client.Name,
+				client.RealAddress,
+				client.VirtualAddress,
+				client.VirtualV6Address,
+				client.Username,
+				strconv.Itoa(client.ClientID),
+				strconv.Itoa(client.PeerID),
+				strconv.FormatInt(client.ConnectedSinceUnix(), 10),
+			}
+			if opts.Counters {
+				fields = append(fields, strconv.FormatInt(client.BytesReceived, 10), strconv.FormatInt(client.BytesSent, 10))
+			}
+			entries = append(entries, fingerprintEntry(fields))
+		}
+		for _, route := range s.Routes {
+			entries = append(entries, fingerprintEntry([]string{"ROUTING_TABLE", route.VirtualAddress, route.CommonName, route.RealAddress}))
+		}
+	}
+	sort.Strings(entries)
+
+	h := sha256.New()
+	for _, entry := range entries {
+		io.WriteString(h, entry)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fingerprintEntry encodes `fields` prefixing each one with its length, so that no two different entries encode the same
+func fingerprintEntry(fields []string) string {
+	var b strings.Builder
+	for _, field := range fields {
+		b.WriteString(strconv.Itoa(len(field)))
+		b.WriteByte(':')
+		b.WriteString(field)
+	}
+	return b.String()
+}
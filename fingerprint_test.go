@@ -0,0 +1,77 @@
+package ovpnstats_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+func TestFingerprint(t *testing.T) {
+	base := mustParse(t, statusV2)
+	fingerprint := base.Fingerprint(ovpnstats.FingerprintOptions{})
+	withCounters := base.Fingerprint(ovpnstats.FingerprintOptions{Counters: true})
+	if fingerprint == withCounters {
+		t.Errorf("Fingerprint is the same with and without Counters")
+	}
+
+	tests := []struct {
+		name        string
+		change      func(s *ovpnstats.Status)
+		wantChanged bool
+		// wantChangedCounters is whether the fingerprint with Counters changes, when it differs from wantChanged
+		wantChangedCounters bool
+	}{
+		{name: "nothing", change: func(s *ovpnstats.Status) {}},
+		{name: "clients reordered", change: func(s *ovpnstats.Status) { s.Clients[0], s.Clients[1] = s.Clients[1], s.Clients[0] }},
+		{name: "routes reordered", change: func(s *ovpnstats.Status) { s.Routes[0], s.Routes[1] = s.Routes[1], s.Routes[0] }},
+		{name: "Title", change: func(s *ovpnstats.Status) { s.Title = "OpenVPN 2.6.9" }},
+		{name: "UpdatedAt", change: func(s *ovpnstats.Status) { s.UpdatedAt = s.UpdatedAt.Add(time.Minute) }},
+		{name: "GLOBAL_STATS", change: func(s *ovpnstats.Status) { s.GlobalStats["Max bcast/mcast queue length"] = "7" }},
+		{name: "Last Ref", change: func(s *ovpnstats.Status) { s.Routes[0].LastRef = s.Routes[0].LastRef.Add(time.Minute) }},
+		{name: "Data Channel Cipher", change: func(s *ovpnstats.Status) { s.Clients[0].DataChannelCipher = "AES-128-GCM" }},
+		{name: "Extra", change: func(s *ovpnstats.Status) { s.Clients[0].Extra = map[string]string{"Pool": "main"} }},
+		{name: "Bytes Received", change: func(s *ovpnstats.Status) { s.Clients[0].BytesReceived++ }, wantChangedCounters: true},
+		{name: "Bytes Sent", change: func(s *ovpnstats.Status) { s.Clients[1].BytesSent++ }, wantChangedCounters: true},
+		{name: "Common Name", change: func(s *ovpnstats.Status) { s.Clients[0].Name = "carol" }, wantChanged: true},
+		{name: "Real Address", change: func(s *ovpnstats.Status) { s.Clients[0].RealAddress = "198.51.100.7:40000" }, wantChanged: true},
+		{name: "Virtual Address", change: func(s *ovpnstats.Status) { s.Clients[0].VirtualAddress = "10.8.0.9" }, wantChanged: true},
+		{name: "Virtual IPv6 Address", change: func(s *ovpnstats.Status) { s.Clients[0].VirtualV6Address = "fd00::9" }, wantChanged: true},
+		{name: "Username", change: func(s *ovpnstats.Status) { s.Clients[1].Username = "bob" }, wantChanged: true},
+		{name: "Client ID", change: func(s *ovpnstats.Status) { s.Clients[0].ClientID = 9 }, wantChanged: true},
+		{name: "Peer ID", change: func(s *ovpnstats.Status) { s.Clients[0].PeerID = 9 }, wantChanged: true},
+		{name: "Connected Since", change: func(s *ovpnstats.Status) { s.Clients[0].ConnectedSince = s.UpdatedAt }, wantChanged: true},
+		{name: "client disconnected", change: func(s *ovpnstats.Status) { s.Clients = s.Clients[:1] }, wantChanged: true},
+		{name: "route removed", change: func(s *ovpnstats.Status) { s.Routes = s.Routes[1:] }, wantChanged: true},
+		{name: "route moved", change: func(s *ovpnstats.Status) { s.Routes[0].CommonName = "bob" }, wantChanged: true},
+		{name: "route Real Address", change: func(s *ovpnstats.Status) { s.Routes[1].RealAddress = "198.51.100.7:40000" }, wantChanged: true},
+		{name: "route Virtual Address", change: func(s *ovpnstats.Status) { s.Routes[1].VirtualAddress = "10.8.0.9" }, wantChanged: true},
+		{
+			name: "fields shifted between columns",
+			change: func(s *ovpnstats.Status) {
+				s.Clients[0].Name, s.Clients[0].RealAddress = "alice203.0.113.5", ":1194"
+			},
+			wantChanged: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := base.Clone()
+			test.change(s)
+			if changed := s.Fingerprint(ovpnstats.FingerprintOptions{}) != fingerprint; changed != test.wantChanged {
+				t.Errorf("Fingerprint changed = %v, want %v", changed, test.wantChanged)
+			}
+			wantChangedCounters := test.wantChanged || test.wantChangedCounters
+			if changed := s.Fingerprint(ovpnstats.FingerprintOptions{Counters: true}) != withCounters; changed != wantChangedCounters {
+				t.Errorf("Fingerprint with Counters changed = %v, want %v", changed, wantChangedCounters)
+			}
+		})
+	}
+}
+
+func TestFingerprintEmpty(t *testing.T) {
+	var nilStatus *ovpnstats.Status
+	if nilStatus.Fingerprint(ovpnstats.FingerprintOptions{}) != (&ovpnstats.Status{}).Fingerprint(ovpnstats.FingerprintOptions{}) {
+		t.Errorf("nil and empty Status have different fingerprints")
+	}
+}
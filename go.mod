@@ -1 +1,3 @@
 module github.com/emibcn/ovpnstats
+
+go 1.27.1
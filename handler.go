@@ -0,0 +1,44 @@
+package ovpnstats
+
+import "io"
+
+// RecordHandler parses the `fields` following the record type of a status file line into `s`, the Status being built.
+// Returning an error stops the parse with it
+type RecordHandler func(fields []string, s *Status) error
+
+// Parser parses status files like ParseStatus does, with RecordHandler registered for custom record types, e.g. the
+// sections added by patched OpenVPN builds, or replacing the built-in parsing of standard ones.
+// Register the handlers before parsing: a Parser is safe for concurrent Parse calls, not for concurrent registrations
+type Parser struct {
+	opts     []Option
+	handlers map[string]RecordHandler
+}
+
+// NewParser returns a Parser using `opts`, which parses every record type like ParseStatus until handlers are registered
+func NewParser(opts ...Option) *Parser {
+	return &Parser{opts: opts, handlers: make(map[string]RecordHandler)}
+}
+
+// RegisterRecordHandler makes `fn` handle the lines of record type `recordType`, replacing any previous handler.
+// It may replace the built-in handling of TITLE, TIME, GLOBAL_STATS, CLIENT_LIST and ROUTING_TABLE, in which case
+// their filters and HEADER columns don't apply; HEADER and END lines structure the file and are always handled by the Parser.
+// Handlers are called sequentially in input order, once per line, and may modify `s` freely. With WithConcurrency,
+// built-in CLIENT_LIST and ROUTING_TABLE records are only added to `s` once the whole input has been read, so handlers
+// must not rely on seeing them. Registering a nil `fn` restores the built-in handling
+func (p *Parser) RegisterRecordHandler(recordType string, fn RecordHandler) {
+	if fn == nil {
+		delete(p.handlers, recordType)
+		return
+	}
+	p.handlers[recordType] = fn
+}
+
+// Parse parses a status file from `r` into a Status
+func (p *Parser) Parse(r io.Reader) (*Status, error) {
+	parser := newParser(newOptions(p.opts))
+	parser.handlers = p.handlers
+	if err := parser.run(r); err != nil {
+		return nil, err
+	}
+	return parser.status, nil
+}
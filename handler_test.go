@@ -0,0 +1,44 @@
+package ovpnstats_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+func TestParserHandlerFirstLine(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		wantVersion int
+	}{
+		{name: "version 2", input: "SERVER_INFO,gw1,eu-west\n" + statusV2, wantVersion: 2},
+		{name: "version 3", input: strings.ReplaceAll("SERVER_INFO,gw1,eu-west\n"+statusV2, ",", "\t"), wantVersion: 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			parser := ovpnstats.NewParser()
+			parser.RegisterRecordHandler("SERVER_INFO", func(fields []string, s *ovpnstats.Status) error {
+				got = fields
+				return nil
+			})
+			status, err := parser.Parse(strings.NewReader(test.input))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if status.Version != test.wantVersion || len(status.Clients) != 2 {
+				t.Errorf("got version %d and %d clients, want version %d and 2 clients", status.Version, len(status.Clients), test.wantVersion)
+			}
+			if len(got) != 2 || got[0] != "gw1" || got[1] != "eu-west" {
+				t.Errorf("SERVER_INFO handler got %q, want [gw1 eu-west]", got)
+			}
+		})
+	}
+
+	// Without the handler, the same first line is an unsupported format
+	if _, err := ovpnstats.NewParser().Parse(strings.NewReader("SERVER_INFO,gw1,eu-west\n" + statusV2)); err == nil {
+		t.Errorf("Parse without handler succeeded, want an UnsupportedVersionError")
+	}
+}
@@ -0,0 +1,145 @@
+package ovpnstats
+
+import (
+	"sync"
+	"time"
+)
+
+// Rate is a throughput in bytes per second received (Rx) and sent (Tx)
+type Rate struct {
+	Rx float64
+	Tx float64
+}
+
+// SessionRate is the throughput of a session between two snapshots
+type SessionRate struct {
+	// Client is the session as seen in the later snapshot
+	Client ClientInfo
+	Rate
+}
+
+// History keeps the last snapshots added to it, up to a fixed capacity, dropping the oldest ones first. It's safe for
+// concurrent use. Snapshots are kept as given, so they must not be modified once added
+type History struct {
+	mu        sync.RWMutex
+	snapshots []*Status
+	// start is the index of the oldest snapshot in snapshots
+	start int
+	len   int
+}
+
+// NewHistory returns an empty History keeping up to `capacity` snapshots. It panics when `capacity` isn't positive
+func NewHistory(capacity int) *History {
+	if capacity <= 0 {
+		panic("ovpnstats: non-positive History capacity")
+	}
+	return &History{snapshots: make([]*Status, capacity)}
+}
+
+// Add adds `s` as the latest snapshot, dropping the oldest one when the History is full
+func (h *History) Add(s *Status) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.len < len(h.snapshots) {
+		h.snapshots[(h.start+h.len)%len(h.snapshots)] = s
+		h.len++
+		return
+	}
+	h.snapshots[h.start] = s
+	h.start = (h.start + 1) % len(h.snapshots)
+}
+
+// Len returns the number of snapshots kept
+func (h *History) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.len
+}
+
+// At returns the `i`th snapshot kept, from 0 for the oldest to Len()-1 for the latest, or nil when `i` is out of range
+func (h *History) At(i int) *Status {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.at(i)
+}
+
+func (h *History) at(i int) *Status {
+	if i < 0 || i >= h.len {
+		return nil
+	}
+	return h.snapshots[(h.start+i)%len(h.snapshots)]
+}
+
+// Latest returns the latest snapshot, or nil when the History is empty
+func (h *History) Latest() *Status {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.at(h.len - 1)
+}
+
+// Rates returns the throughput of the sessions present in both of the two latest snapshots, over the time elapsed
+// between their UpdatedAt. Sessions are matched like Diff does and counter resets are handled like SessionChange.Traffic,
+// while ok is false when there are fewer than two snapshots or their UpdatedAt don't move forward, e.g. when missing
+func (h *History) Rates() (rates []SessionRate, ok bool) {
+	h.mu.RLock()
+	prev, curr := h.at(h.len-2), h.at(h.len-1)
+	h.mu.RUnlock()
+	if prev == nil || curr == nil || prev.UpdatedAt.IsZero() {
+		return nil, false
+	}
+	elapsed := curr.UpdatedAt.Sub(prev.UpdatedAt).Seconds()
+	if elapsed <= 0 {
+		return nil, false
+	}
+
+	for _, change := range Diff(prev, curr).Remaining {
+		traffic := change.Traffic()
+		rates = append(rates, SessionRate{
+			Client: change.Current,
+			Rate:   Rate{Rx: float64(traffic.Rx) / elapsed, Tx: float64(traffic.Tx) / elapsed},
+		})
+	}
+	return rates, true
+}
+
+// Churn is a rate of connections and disconnections per minute
+type Churn struct {
+	Connects    float64
+	Disconnects float64
+}
+
+// ChurnRate returns the rate of connections and disconnections over the snapshots whose UpdatedAt is within `window`
+// of the latest one, counted from the Diff of each pair of consecutive snapshots and divided by the time elapsed
+// from the first to the last of them. Sessions are matched like Diff does, so a reconnection, which starts a new
+// session, counts as both a disconnection and a connection. Nil snapshots and those without UpdatedAt are skipped.
+// ok is false when the latest snapshot is one of them, or when fewer than two snapshots, with UpdatedAt moving forward,
+// are in the window
+func (h *History) ChurnRate(window time.Duration) (churn Churn, ok bool) {
+	h.mu.RLock()
+	latest := h.at(h.len - 1)
+	if latest != nil && latest.UpdatedAt.IsZero() {
+		latest = nil
+	}
+	var snapshots []*Status
+	for i := 0; latest != nil && i < h.len; i++ {
+		if s := h.at(i); s != nil && !s.UpdatedAt.IsZero() && latest.UpdatedAt.Sub(s.UpdatedAt) <= window {
+			snapshots = append(snapshots, s)
+		}
+	}
+	h.mu.RUnlock()
+	if len(snapshots) < 2 {
+		return Churn{}, false
+	}
+	elapsed := snapshots[len(snapshots)-1].UpdatedAt.Sub(snapshots[0].UpdatedAt).Minutes()
+	if elapsed <= 0 {
+		return Churn{}, false
+	}
+
+	var connects, disconnects int
+	for i := 1; i < len(snapshots); i++ {
+		diff := Diff(snapshots[i-1], snapshots[i])
+		connects += len(diff.Connected)
+		disconnects += len(diff.Disconnected)
+	}
+	return Churn{Connects: float64(connects) / elapsed, Disconnects: float64(disconnects) / elapsed}, true
+}
@@ -0,0 +1,199 @@
+package ovpnstats_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+// snapshot returns a Status written at `at` (a time_t) with `clients`
+func snapshot(at int64, clients ...ovpnstats.ClientInfo) *ovpnstats.Status {
+	return &ovpnstats.Status{UpdatedAt: time.Unix(at, 0), Clients: clients}
+}
+
+// traffic returns the session of `name` connected at 1614589200 with the given counters
+func traffic(name string, rx, tx int64) ovpnstats.ClientInfo {
+	client := session(name, 0, 0, 1614589200)
+	client.BytesReceived, client.BytesSent = rx, tx
+	return client
+}
+
+func TestHistoryWraparound(t *testing.T) {
+	h := ovpnstats.NewHistory(3)
+	if h.Latest() != nil || h.At(0) != nil || h.Len() != 0 {
+		t.Fatalf("empty History has Latest %v, At(0) %v and Len %d", h.Latest(), h.At(0), h.Len())
+	}
+	var added []*ovpnstats.Status
+	for i := int64(0); i < 5; i++ {
+		s := snapshot(1614592800 + 60*i)
+		added = append(added, s)
+		h.Add(s)
+	}
+	if h.Len() != 3 {
+		t.Fatalf("Len() = %d, want 3", h.Len())
+	}
+	for i, want := range added[2:] {
+		if got := h.At(i); got != want {
+			t.Errorf("At(%d) = %v, want snapshot %d", i, got, i+2)
+		}
+	}
+	if h.At(-1) != nil || h.At(3) != nil {
+		t.Errorf("At out of range = %v and %v, want nil", h.At(-1), h.At(3))
+	}
+	if h.Latest() != added[4] {
+		t.Errorf("Latest() = %v, want the last snapshot added", h.Latest())
+	}
+}
+
+func TestHistoryRates(t *testing.T) {
+	tests := []struct {
+		name      string
+		snapshots []*ovpnstats.Status
+		want      map[string]ovpnstats.Rate
+		wantOK    bool
+	}{
+		{name: "empty"},
+		{name: "single snapshot", snapshots: []*ovpnstats.Status{snapshot(1614592800, traffic("alice", 0, 0))}},
+		{
+			name:      "growth",
+			snapshots: []*ovpnstats.Status{snapshot(1614592800, traffic("alice", 1000, 2000)), snapshot(1614592810, traffic("alice", 2000, 2500))},
+			want:      map[string]ovpnstats.Rate{"alice": {Rx: 100, Tx: 50}},
+			wantOK:    true,
+		},
+		{
+			name: "two latest snapshots after wraparound",
+			snapshots: []*ovpnstats.Status{
+				snapshot(1614592700, traffic("alice", 0, 0)),
+				snapshot(1614592750, traffic("alice", 500, 500)),
+				snapshot(1614592800, traffic("alice", 1000, 1000)),
+				snapshot(1614592804, traffic("alice", 1400, 1200)),
+			},
+			want:   map[string]ovpnstats.Rate{"alice": {Rx: 100, Tx: 50}},
+			wantOK: true,
+		},
+		{
+			name:      "counter reset",
+			snapshots: []*ovpnstats.Status{snapshot(1614592800, traffic("alice", 5000, 5000)), snapshot(1614592810, traffic("alice", 300, 100))},
+			want:      map[string]ovpnstats.Rate{"alice": {Rx: 30, Tx: 10}},
+			wantOK:    true,
+		},
+		{
+			name:      "new and gone sessions",
+			snapshots: []*ovpnstats.Status{snapshot(1614592800, traffic("alice", 0, 0), traffic("bob", 0, 0)), snapshot(1614592810, traffic("alice", 10, 20), traffic("carol", 500, 500))},
+			want:      map[string]ovpnstats.Rate{"alice": {Rx: 1, Tx: 2}},
+			wantOK:    true,
+		},
+		{
+			name:      "zero time delta",
+			snapshots: []*ovpnstats.Status{snapshot(1614592800, traffic("alice", 0, 0)), snapshot(1614592800, traffic("alice", 10, 20))},
+		},
+		{
+			name:      "time going backwards",
+			snapshots: []*ovpnstats.Status{snapshot(1614592810, traffic("alice", 0, 0)), snapshot(1614592800, traffic("alice", 10, 20))},
+		},
+		{
+			name:      "unknown time",
+			snapshots: []*ovpnstats.Status{{Clients: []ovpnstats.ClientInfo{traffic("alice", 0, 0)}}, snapshot(1614592800, traffic("alice", 10, 20))},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := ovpnstats.NewHistory(3)
+			for _, s := range test.snapshots {
+				h.Add(s)
+			}
+			rates, ok := h.Rates()
+			if ok != test.wantOK {
+				t.Fatalf("Rates() ok = %v, want %v", ok, test.wantOK)
+			}
+			got := make(map[string]ovpnstats.Rate, len(rates))
+			for _, rate := range rates {
+				got[rate.Client.Name] = rate.Rate
+			}
+			if len(got) != len(test.want) || (len(got) > 0 && !reflect.DeepEqual(got, test.want)) {
+				t.Errorf("Rates() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewHistoryCapacity(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("NewHistory(0) didn't panic")
+		}
+	}()
+	ovpnstats.NewHistory(0)
+}
+
+func TestHistoryChurnRate(t *testing.T) {
+	alice, bob, carol := traffic("alice", 0, 0), traffic("bob", 0, 0), traffic("carol", 0, 0)
+	aliceReconnected := session("alice", 3, 0, 1614592860)
+	tests := []struct {
+		name      string
+		snapshots []*ovpnstats.Status
+		window    time.Duration
+		want      ovpnstats.Churn
+		wantOK    bool
+	}{
+		{name: "empty", window: time.Hour},
+		{name: "single snapshot", snapshots: []*ovpnstats.Status{snapshot(1614592800, alice)}, window: time.Hour},
+		{
+			name:      "connect and disconnect",
+			snapshots: []*ovpnstats.Status{snapshot(1614592800, alice), snapshot(1614592860, alice, bob), snapshot(1614592920, bob, carol)},
+			window:    time.Hour,
+			want:      ovpnstats.Churn{Connects: 1, Disconnects: 0.5},
+			wantOK:    true,
+		},
+		{
+			name:      "reconnection counts as both",
+			snapshots: []*ovpnstats.Status{snapshot(1614592800, alice), snapshot(1614592860, aliceReconnected)},
+			window:    time.Hour,
+			want:      ovpnstats.Churn{Connects: 1, Disconnects: 1},
+			wantOK:    true,
+		},
+		{
+			name:      "snapshots out of the window",
+			snapshots: []*ovpnstats.Status{snapshot(1614589200), snapshot(1614592800, alice), snapshot(1614592920, alice, bob, carol)},
+			window:    5 * time.Minute,
+			want:      ovpnstats.Churn{Connects: 1},
+			wantOK:    true,
+		},
+		{
+			name:      "zero time delta",
+			snapshots: []*ovpnstats.Status{snapshot(1614592800, alice), snapshot(1614592800, bob)},
+			window:    time.Hour,
+		},
+		{
+			name:      "nil and unknown time snapshots skipped",
+			snapshots: []*ovpnstats.Status{snapshot(1614592800), nil, {Clients: []ovpnstats.ClientInfo{carol}}, snapshot(1614592860, alice)},
+			window:    time.Hour,
+			want:      ovpnstats.Churn{Connects: 1},
+			wantOK:    true,
+		},
+		{
+			name:      "latest snapshot without time",
+			snapshots: []*ovpnstats.Status{snapshot(1614592800), snapshot(1614592860, alice), {Clients: []ovpnstats.ClientInfo{alice}}},
+			window:    time.Hour,
+		},
+		{
+			name:      "latest snapshot nil",
+			snapshots: []*ovpnstats.Status{snapshot(1614592800), snapshot(1614592860, alice), nil},
+			window:    time.Hour,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := ovpnstats.NewHistory(4)
+			for _, s := range test.snapshots {
+				h.Add(s)
+			}
+			churn, ok := h.ChurnRate(test.window)
+			if ok != test.wantOK || churn != test.want {
+				t.Errorf("ChurnRate(%v) = %+v, %v, want %+v, %v", test.window, churn, ok, test.want, test.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package ovpnstats
+
+import "sort"
+
+// IdentityKind selects what identifies a user across snapshots
+type IdentityKind int
+
+const (
+	// IdentityCommonName identifies users by the Common Name of their certificate
+	IdentityCommonName IdentityKind = iota
+	// IdentityUsername identifies users by the Username they authenticated with.
+	// Clients without one, shown by OpenVPN as "UNDEF", aren't counted
+	IdentityUsername
+)
+
+// identity returns the identity of `c` of kind `by`, or "" when it has none
+func (c ClientInfo) identity(by IdentityKind) string {
+	if by == IdentityUsername {
+		if c.Username == undefined {
+			return ""
+		}
+		return c.Username
+	}
+	return c.Name
+}
+
+// UniqueClients returns the number of distinct identities of kind `by` among the clients of all `snapshots`, e.g. the
+// monthly active users from the snapshots of a month. Identities are compared exactly, so each one counts once however
+// many sessions, concurrent or not, it had. Nil snapshots are ignored
+func UniqueClients(snapshots []*Status, by IdentityKind) int {
+	return len(UniqueClientSet(snapshots, by))
+}
+
+// UniqueClientSet returns, sorted, the distinct identities of kind `by` counted by UniqueClients
+func UniqueClientSet(snapshots []*Status, by IdentityKind) []string {
+	seen := make(map[string]bool)
+	var identities []string
+	for _, s := range snapshots {
+		if s == nil {
+			continue
+		}
+		for _, client := range s.Clients {
+			if id := client.identity(by); id != "" && !seen[id] {
+				seen[id] = true
+				identities = append(identities, id)
+			}
+		}
+	}
+	sort.Strings(identities)
+	return identities
+}
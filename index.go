@@ -0,0 +1,89 @@
+package ovpnstats
+
+import "sort"
+
+// Index provides constant time lookups of the clients of a Status.
+// It's built once by NewIndex and never modified afterwards, so it's safe for concurrent reads.
+// It doesn't follow later changes to the Status it was built from.
+// Lookups never allocate, as they return the slices and copies held by the Index instead of building new ones,
+// so they're fit for hot paths. Only NewIndex allocates
+type Index struct {
+	byCommonName map[string][]ClientInfo
+	byUsername   map[string][]ClientInfo
+	byClientID   map[int]ClientInfo
+	byPeerID     map[int]ClientInfo
+}
+
+// NewIndex builds an Index of the clients in `s`
+func NewIndex(s *Status) *Index {
+	index := &Index{
+		byCommonName: make(map[string][]ClientInfo, len(s.Clients)),
+		byUsername:   make(map[string][]ClientInfo),
+		byClientID:   make(map[int]ClientInfo, len(s.Clients)),
+		byPeerID:     make(map[int]ClientInfo, len(s.Clients)),
+	}
+	for _, client := range s.Clients {
+		index.byCommonName[client.Name] = append(index.byCommonName[client.Name], client)
+		index.byUsername[client.Username] = append(index.byUsername[client.Username], client)
+		index.byClientID[client.ClientID] = client
+		index.byPeerID[client.PeerID] = client
+	}
+	return index
+}
+
+// ByCommonName returns the clients with Common Name `name`, several of them when duplicate-cn is enabled.
+// The returned slice is shared and must not be modified
+func (i *Index) ByCommonName(name string) []ClientInfo {
+	return i.byCommonName[name]
+}
+
+// ByUsername returns the clients authenticated as `username`.
+// The returned slice is shared and must not be modified
+func (i *Index) ByUsername(username string) []ClientInfo {
+	return i.byUsername[username]
+}
+
+// ByClientID returns the client with Client ID `id`
+func (i *Index) ByClientID(id int) (ClientInfo, bool) {
+	client, ok := i.byClientID[id]
+	return client, ok
+}
+
+// ByPeerID returns the client with Peer ID `id`
+func (i *Index) ByPeerID(id int) (ClientInfo, bool) {
+	client, ok := i.byPeerID[id]
+	return client, ok
+}
+
+// ClientMap returns the clients of `s` keyed by Common Name. When several clients share a Common Name (duplicate-cn),
+// the last one in client list order wins: use ClientMultiMap when DuplicateCommonNames isn't empty
+func (s *Status) ClientMap() map[string]ClientInfo {
+	clients := make(map[string]ClientInfo, len(s.Clients))
+	for _, client := range s.Clients {
+		clients[client.Name] = client
+	}
+	return clients
+}
+
+// ClientMultiMap returns the clients of `s` keyed by Common Name, keeping all the sessions of each one in client list order
+func (s *Status) ClientMultiMap() map[string][]ClientInfo {
+	clients := make(map[string][]ClientInfo, len(s.Clients))
+	for _, client := range s.Clients {
+		clients[client.Name] = append(clients[client.Name], client)
+	}
+	return clients
+}
+
+// DuplicateCommonNames returns, sorted, the Common Names shared by several clients of `s`
+func (s *Status) DuplicateCommonNames() []string {
+	counts := make(map[string]int, len(s.Clients))
+	var duplicates []string
+	for _, client := range s.Clients {
+		counts[client.Name]++
+		if counts[client.Name] == 2 {
+			duplicates = append(duplicates, client.Name)
+		}
+	}
+	sort.Strings(duplicates)
+	return duplicates
+}
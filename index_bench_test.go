@@ -0,0 +1,62 @@
+package ovpnstats_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+// benchmarkIndex returns the Index of a status file with benchmarkClients clients
+func benchmarkIndex(b *testing.B) *ovpnstats.Index {
+	b.Helper()
+	status, err := ovpnstats.ParseStatus(bytes.NewReader(statusFile(benchmarkClients)))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return ovpnstats.NewIndex(status)
+}
+
+func BenchmarkIndexByCommonName(b *testing.B) {
+	index := benchmarkIndex(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if len(index.ByCommonName("client4242")) != 1 {
+			b.Fatal("client4242 not found")
+		}
+	}
+}
+
+func BenchmarkIndexByUsername(b *testing.B) {
+	index := benchmarkIndex(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if len(index.ByUsername("user4242")) != 1 {
+			b.Fatal("user4242 not found")
+		}
+	}
+}
+
+func BenchmarkIndexByClientID(b *testing.B) {
+	index := benchmarkIndex(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok := index.ByClientID(4242); !ok {
+			b.Fatal("Client ID 4242 not found")
+		}
+	}
+}
+
+func BenchmarkIndexByPeerID(b *testing.B) {
+	index := benchmarkIndex(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok := index.ByPeerID(4242); !ok {
+			b.Fatal("Peer ID 4242 not found")
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package ovpnstats_test
+
+import (
+	"testing"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+func TestIndexLookupsDontAllocate(t *testing.T) {
+	index := ovpnstats.NewIndex(mustParse(t, statusV2))
+	tests := []struct {
+		name   string
+		lookup func()
+	}{
+		{name: "ByCommonName", lookup: func() { index.ByCommonName("alice") }},
+		{name: "ByCommonName missing", lookup: func() { index.ByCommonName("mallory") }},
+		{name: "ByUsername", lookup: func() { index.ByUsername("alice") }},
+		{name: "ByClientID", lookup: func() { index.ByClientID(1) }},
+		{name: "ByPeerID", lookup: func() { index.ByPeerID(1) }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if allocs := testing.AllocsPerRun(100, test.lookup); allocs != 0 {
+				t.Errorf("%s allocates %v times per lookup, want 0", test.name, allocs)
+			}
+		})
+	}
+}
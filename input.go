@@ -0,0 +1,130 @@
+package ovpnstats
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrInputTooLarge is returned when the input exceeds the size given to WithMaxBytes
+var ErrInputTooLarge = errors.New("ovpnstats: input too large")
+
+// inputReader enforces the size and time limits of a parse on its input
+type inputReader struct {
+	r       io.Reader
+	ctx     context.Context
+	limited *io.LimitedReader
+	limit   int64
+	failure error
+}
+
+// input wraps `r` with the limits set in the options
+func (o *options) input(r io.Reader) *inputReader {
+	in := &inputReader{r: r, ctx: o.ctx}
+	if o.maxBytes > 0 {
+		// Allow a byte more than the limit to find out whether it's exceeded
+		in.limited = &io.LimitedReader{R: r, N: o.maxBytes + 1}
+		in.r = in.limited
+		in.limit = o.maxBytes
+	}
+	return in
+}
+
+func (in *inputReader) Read(p []byte) (int, error) {
+	if in.failure != nil {
+		return 0, in.failure
+	}
+	if in.ctx != nil {
+		if err := in.ctx.Err(); err != nil {
+			in.failure = err
+			return 0, err
+		}
+	}
+	n, err := in.r.Read(p)
+	if in.limited != nil && in.limited.N == 0 {
+		in.failure = fmt.Errorf("%w: more than %d bytes", ErrInputTooLarge, in.limit)
+		if n > 0 {
+			n--
+		}
+		return n, in.failure
+	}
+	return n, err
+}
+
+// err returns the limit which stopped the input, if any
+func (in *inputReader) err() error {
+	return in.failure
+}
+
+// lineScanner reads the input line by line, like bufio.Scanner
+type lineScanner interface {
+	Scan() bool
+	Text() string
+	Err() error
+}
+
+// readerLines reads lines straight from a bufio.Reader, never reading past the line returned,
+// enforcing the size and time limits of a parse
+type readerLines struct {
+	r     *bufio.Reader
+	ctx   context.Context
+	limit int64
+	read  int64
+	line  string
+	err   error
+}
+
+// readerLines reads lines from `r` with the limits set in the options
+func (o *options) readerLines(r *bufio.Reader) *readerLines {
+	return &readerLines{r: r, ctx: o.ctx, limit: o.maxBytes}
+}
+
+// Scan reads the next line, reporting whether there's one
+func (l *readerLines) Scan() bool {
+	if l.err != nil {
+		return false
+	}
+	if l.ctx != nil {
+		if err := l.ctx.Err(); err != nil {
+			l.err = err
+			return false
+		}
+	}
+	// Read the line a buffer at a time, so that the limit is enforced before buffering the whole of a long line
+	var line []byte
+	for {
+		chunk, err := l.r.ReadSlice('\n')
+		l.read += int64(len(chunk))
+		if l.limit > 0 && l.read > l.limit {
+			l.err = fmt.Errorf("%w: more than %d bytes", ErrInputTooLarge, l.limit)
+			return false
+		}
+		line = append(line, chunk...)
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			if err != io.EOF {
+				l.err = err
+			}
+			return false
+		}
+		break
+	}
+	// Drop the line break like bufio.ScanLines does
+	l.line = strings.TrimSuffix(strings.TrimSuffix(string(line), "\n"), "\r")
+	return true
+}
+
+// Text returns the last line read
+func (l *readerLines) Text() string {
+	return l.line
+}
+
+// Err returns the error which stopped reading, if any but io.EOF
+func (l *readerLines) Err() error {
+	return l.err
+}
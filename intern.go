@@ -0,0 +1,73 @@
+package ovpnstats
+
+import "sync"
+
+// StringTable holds a single copy of each string interned in it, to be shared by the snapshots parsed
+// WithStringInterning. It only grows: drop it, and the snapshots using it, to release its strings.
+// It's safe for concurrent use
+type StringTable struct {
+	mu      sync.Mutex
+	strings map[string]string
+}
+
+// NewStringTable returns an empty StringTable
+func NewStringTable() *StringTable {
+	return &StringTable{strings: make(map[string]string)}
+}
+
+// Len returns the number of distinct strings in the table
+func (t *StringTable) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.strings)
+}
+
+// intern returns the copy of `s` in the table, adding it when missing.
+// New strings are copied so they don't keep the whole line they were split from alive
+func (t *StringTable) intern(s string) string {
+	if s == "" {
+		return ""
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if interned, ok := t.strings[s]; ok {
+		return interned
+	}
+	interned := string([]byte(s))
+	t.strings[interned] = interned
+	return interned
+}
+
+// internClient interns the string fields of `c`, including its Extra and Metadata
+func (t *StringTable) internClient(c ClientInfo) ClientInfo {
+	c.Name = t.intern(c.Name)
+	c.RealAddress = t.intern(c.RealAddress)
+	c.VirtualAddress = t.intern(c.VirtualAddress)
+	c.VirtualV6Address = t.intern(c.VirtualV6Address)
+	c.Username = t.intern(c.Username)
+	c.DataChannelCipher = t.intern(c.DataChannelCipher)
+	c.Protocol = t.intern(c.Protocol)
+	c.Extra = t.internStrings(c.Extra)
+	c.Metadata = t.internStrings(c.Metadata)
+	return c
+}
+
+// internStrings returns a copy of `m` with its keys and values interned, nil when `m` is nil
+func (t *StringTable) internStrings(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	interned := make(map[string]string, len(m))
+	for k, v := range m {
+		interned[t.intern(k)] = t.intern(v)
+	}
+	return interned
+}
+
+// internRoute interns the string fields of `r`
+func (t *StringTable) internRoute(r RoutingInfo) RoutingInfo {
+	r.VirtualAddress = t.intern(r.VirtualAddress)
+	r.CommonName = t.intern(r.CommonName)
+	r.RealAddress = t.intern(r.RealAddress)
+	return r
+}
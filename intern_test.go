@@ -0,0 +1,54 @@
+package ovpnstats_test
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+// sameMemory reports whether `a` and `b` are the same bytes in memory, not just equal
+func sameMemory(a, b string) bool {
+	return a == b && unsafe.StringData(a) == unsafe.StringData(b)
+}
+
+func TestWithStringInterning(t *testing.T) {
+	table := ovpnstats.NewStringTable()
+	first := mustParse(t, statusV2, ovpnstats.WithStringInterning(table))
+	second := mustParse(t, statusV2, ovpnstats.WithStringInterning(table))
+	size := table.Len()
+	if size == 0 {
+		t.Fatalf("table is empty after parsing")
+	}
+
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{name: "Common Name across sections", a: first.Clients[0].Name, b: first.Routes[0].CommonName},
+		{name: "Common Name and Username", a: first.Clients[0].Name, b: first.Clients[0].Username},
+		{name: "Real Address across sections", a: first.Clients[1].RealAddress, b: first.Routes[1].RealAddress},
+		{name: "Common Name across snapshots", a: first.Clients[0].Name, b: second.Clients[0].Name},
+		{name: "cipher across snapshots", a: first.Clients[1].DataChannelCipher, b: second.Clients[1].DataChannelCipher},
+		{name: "Virtual Address across snapshots", a: first.Routes[0].VirtualAddress, b: second.Routes[0].VirtualAddress},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if !sameMemory(test.a, test.b) {
+				t.Errorf("%q and %q don't share their memory", test.a, test.b)
+			}
+		})
+	}
+
+	mustParse(t, statusV2, ovpnstats.WithStringInterning(table))
+	if table.Len() != size {
+		t.Errorf("Len() = %d after parsing the same status again, want %d", table.Len(), size)
+	}
+}
+
+func TestWithoutStringInterning(t *testing.T) {
+	s := mustParse(t, statusV2)
+	if sameMemory(s.Clients[0].Name, s.Routes[0].CommonName) {
+		t.Errorf("Common Names share their memory without WithStringInterning")
+	}
+}
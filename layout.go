@@ -0,0 +1,134 @@
+package ovpnstats
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// layout maps the column names of a record type, as given by its HEADER line, to their position in the record's fields
+type layout struct {
+	names []string
+	index map[string]int
+	// required is the number of columns every record must have
+	required int
+	// assumed is set when the layout isn't given by a HEADER line
+	assumed bool
+}
+
+// newLayout returns the layout given by a HEADER line, of records whose fields after the record type are the columns `names`
+func newLayout(names []string) *layout {
+	l := &layout{names: names, index: make(map[string]int, len(names)), required: len(names)}
+	for i, name := range names {
+		if _, ok := l.index[name]; !ok {
+			l.index[name] = i + 1
+		}
+	}
+	return l
+}
+
+// assumedLayout returns the layout assumed for records without HEADER, whose columns are `names`,
+// of which only the first `required` ones are mandatory
+func assumedLayout(names []string, required int) *layout {
+	l := newLayout(names)
+	l.required, l.assumed = required, true
+	return l
+}
+
+// check fails when the `recordType` record `fields` lack some of the columns of the layout, e.g. when truncated
+func (l *layout) check(recordType string, fields []string) error {
+	n := len(fields) - 1
+	switch {
+	case n >= l.required:
+		return nil
+	case l.assumed:
+		return fmt.Errorf("ovpnstats: %s has %d fields, expected at least %d", recordType, n, l.required)
+	default:
+		return fmt.Errorf("ovpnstats: %s has %d fields, HEADER has %d", recordType, n, l.required)
+	}
+}
+
+// field returns the value of column `name` in `fields`. ok is false when the record has no such column
+func (l *layout) field(fields []string, name string) (value string, ok bool) {
+	i, ok := l.index[name]
+	if !ok || i >= len(fields) {
+		return "", false
+	}
+	return fields[i], true
+}
+
+// string returns the value of column `name` in `fields`, or "" when the record has no such column
+func (l *layout) string(fields []string, name string) string {
+	value, _ := l.field(fields, name)
+	return value
+}
+
+// int returns the integer value of column `name` in `fields`, or 0 when the record has no such column
+func (l *layout) int(fields []string, name string) (int, error) {
+	value, ok := l.field(fields, name)
+	if !ok {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// bytes returns the byte counter of column `name` in `fields`, or 0 when the record has no such column.
+// Counters are unsigned, and those beyond the int64 range saturate to math.MaxInt64 rather than failing the whole record
+func (l *layout) bytes(fields []string, name string) (int64, error) {
+	value, ok := l.field(fields, name)
+	if !ok {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(value, 10, 64)
+	if errors.Is(err, strconv.ErrRange) || n > math.MaxInt64 {
+		return math.MaxInt64, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("ovpnstats: invalid %s %q", name, value)
+	}
+	return int64(n), nil
+}
+
+// ungroup removes the thousands separator `grouping` from the values of `columns` in `fields`, in place
+func (l *layout) ungroup(fields []string, columns []string, grouping rune) {
+	for _, name := range columns {
+		if i, ok := l.index[name]; ok && i < len(fields) {
+			fields[i] = strings.ReplaceAll(fields[i], string(grouping), "")
+		}
+	}
+}
+
+// metadataColumnPrefix prefixes the names of the columns holding ClientInfo.Metadata
+const metadataColumnPrefix = "meta:"
+
+// extra returns the columns of `fields` not in `known`, nor metadata, keyed by name, or nil if there are none
+func (l *layout) extra(fields []string, known map[string]bool) map[string]string {
+	var extra map[string]string
+	for i, name := range l.names {
+		if known[name] || strings.HasPrefix(name, metadataColumnPrefix) || i+1 >= len(fields) {
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]string)
+		}
+		extra[name] = fields[i+1]
+	}
+	return extra
+}
+
+// metadata returns the non empty metadata columns of `fields`, keyed by name without prefix, or nil if there are none
+func (l *layout) metadata(fields []string) map[string]string {
+	var metadata map[string]string
+	for i, name := range l.names {
+		if !strings.HasPrefix(name, metadataColumnPrefix) || i+1 >= len(fields) || fields[i+1] == "" {
+			continue
+		}
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata[strings.TrimPrefix(name, metadataColumnPrefix)] = fields[i+1]
+	}
+	return metadata
+}
@@ -0,0 +1,195 @@
+package ovpnstats
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// passwordPrompt is sent by a password protected management interface, without a line break
+const passwordPrompt = "ENTER PASSWORD:"
+
+// ErrAuthFailed is returned when the management interface rejects the password
+var ErrAuthFailed = errors.New("ovpnstats: management interface authentication failed")
+
+// ErrPasswordRequired is returned when the management interface asks for a password but none is set
+var ErrPasswordRequired = errors.New("ovpnstats: management interface requires a password")
+
+// ManagementClient retrieves the status from the OpenVPN management interface.
+// Each call to Status opens its own connection, so a ManagementClient can be used concurrently
+type ManagementClient struct {
+	// Network is "tcp" (default) or "unix"
+	Network string
+	// Address is the host:port or socket path given to OpenVPN's --management
+	Address string
+	// Password is sent when the management interface asks for it
+	Password string
+	// Version is the status format requested, 2 (default) or 3
+	Version int
+}
+
+// Status connects to the management interface, authenticates if it asks for a password, issues the
+// "status" command and parses its output with `opts`. ctx bounds the whole exchange.
+// It fails with ErrPasswordRequired when a password is asked but Password is empty, and ErrAuthFailed when it's rejected
+func (m *ManagementClient) Status(ctx context.Context, opts ...Option) (*Status, error) {
+	network := m.Network
+	if network == "" {
+		network = "tcp"
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, m.Address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return m.status(ctx, conn, opts)
+}
+
+// status retrieves the status over the management interface connection `conn`, bounded by ctx
+func (m *ManagementClient) status(ctx context.Context, conn net.Conn, opts []Option) (*Status, error) {
+	version := m.Version
+	if version == 0 {
+		version = 2
+	}
+
+	// Unblock any pending read or write once ctx is done
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Unix(1, 0))
+		case <-done:
+		}
+	}()
+
+	output, err := m.exchange(bufio.NewReader(conn), conn, version)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+	return ParseStatus(bytes.NewReader(output), opts...)
+}
+
+// exchange runs the management protocol over `r` and `w` and returns the raw status output
+func (m *ManagementClient) exchange(r *bufio.Reader, w io.Writer, version int) ([]byte, error) {
+	if err := m.handshake(r, w); err != nil {
+		return nil, err
+	}
+
+	if _, err := fmt.Fprintf(w, "status %d\n", version); err != nil {
+		return nil, err
+	}
+	var output bytes.Buffer
+	for {
+		line, err := readManagementLine(r)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case strings.HasPrefix(line, "ERROR:"):
+			return nil, fmt.Errorf("ovpnstats: management status command failed: %q", line)
+		case strings.HasPrefix(line, ">"):
+			// Real-time notifications and the rest of the banner may be interleaved with the command output
+			continue
+		}
+		output.WriteString(line)
+		output.WriteByte('\n')
+		if line == "END" {
+			break
+		}
+	}
+	fmt.Fprintf(w, "quit\n")
+	return output.Bytes(), nil
+}
+
+// handshake consumes the management interface greeting until it accepts commands:
+// the optional "ENTER PASSWORD:" prompt, answered with m.Password and acknowledged with "SUCCESS:" (or rejected with "ERROR:"),
+// and the first ">INFO:" line of the banner. Any other ">" notification is skipped
+func (m *ManagementClient) handshake(r *bufio.Reader, w io.Writer) error {
+	prompted, authenticated := false, false
+	for {
+		prompt, err := atPasswordPrompt(r)
+		var line string
+		if err == nil && !prompt {
+			line, err = readManagementLine(r)
+		}
+		if err != nil {
+			if prompted && !authenticated && err == io.EOF {
+				return ErrAuthFailed
+			}
+			return err
+		}
+		if prompt {
+			r.Discard(len(passwordPrompt))
+			if m.Password == "" {
+				return ErrPasswordRequired
+			}
+			if _, err := fmt.Fprintf(w, "%s\n", m.Password); err != nil {
+				return err
+			}
+			prompted = true
+			continue
+		}
+
+		switch {
+		case prompted && !authenticated && strings.HasPrefix(line, "SUCCESS:"):
+			authenticated = true
+		case prompted && !authenticated && strings.HasPrefix(line, "ERROR:"):
+			return fmt.Errorf("%w: %q", ErrAuthFailed, line)
+		case strings.HasPrefix(line, ">INFO:"):
+			if prompted && !authenticated {
+				return fmt.Errorf("ovpnstats: unexpected management banner before authentication: %q", line)
+			}
+			return nil
+		case strings.HasPrefix(line, ">"), line == "":
+			continue
+		default:
+			return fmt.Errorf("ovpnstats: unexpected management greeting %q", line)
+		}
+	}
+}
+
+// atPasswordPrompt reports whether `r` is at the password prompt, which has no line break so it can't be read as a line.
+// It only waits for more input while the bytes received so far are the start of the prompt, so that a greeting line
+// shorter than the prompt doesn't block until the prompt's length is received
+func atPasswordPrompt(r *bufio.Reader) (bool, error) {
+	n := 1
+	for {
+		received, err := r.Peek(n)
+		if err != nil {
+			return false, err
+		}
+		if !strings.HasPrefix(passwordPrompt, string(received)) {
+			return false, nil
+		}
+		if n == len(passwordPrompt) {
+			return true, nil
+		}
+		n++
+		if buffered := r.Buffered(); buffered > n {
+			n = buffered
+		}
+		if n > len(passwordPrompt) {
+			n = len(passwordPrompt)
+		}
+	}
+}
+
+// readManagementLine reads a single line from the management interface, without its line terminator
+func readManagementLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,295 @@
+package ovpnstats
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	// managementBanner is the greeting of the management interface once it accepts commands
+	managementBanner = ">INFO:OpenVPN Management Interface Version 5 -- type 'help' for more info\n"
+	// managementStatus is the reply to "status 2", terminated by its END line
+	managementStatus = "TITLE,OpenVPN 2.6.8 x86_64-pc-linux-gnu\n" +
+		"TIME,2021-03-01 10:00:00,1614592800\n" +
+		"HEADER,CLIENT_LIST,Common Name,Real Address,Virtual Address,Virtual IPv6 Address,Bytes Received,Bytes Sent,Connected Since,Connected Since (time_t),Username,Client ID,Peer ID,Data Channel Cipher\n" +
+		"CLIENT_LIST,alice,203.0.113.5:1194,10.8.0.2,,100,200,2021-03-01 09:00:00,1614589200,alice,0,0,AES-256-GCM\n" +
+		"HEADER,ROUTING_TABLE,Virtual Address,Common Name,Real Address,Last Ref,Last Ref (time_t)\n" +
+		"ROUTING_TABLE,10.8.0.2,alice,203.0.113.5:1194,2021-03-01 09:59:00,1614592740\n" +
+		"GLOBAL_STATS,Max bcast/mcast queue length,0\n" +
+		"END\n"
+)
+
+// managementStep is a step of a fake management interface: sending `send` as is, reading the line `expect`,
+// or closing the connection when `hangUp` is set
+type managementStep struct {
+	send   string
+	expect string
+	hangUp bool
+}
+
+// send returns a step sending `s`
+func send(s string) managementStep {
+	return managementStep{send: s}
+}
+
+// expect returns a step reading the line `line` from the client
+func expect(line string) managementStep {
+	return managementStep{expect: line}
+}
+
+// hangUp returns a step closing the connection
+func hangUp() managementStep {
+	return managementStep{hangUp: true}
+}
+
+// serveManagement plays `steps` as a management interface on `conn`, then reads until the client closes its end.
+// The returned channel is closed once it's done
+func serveManagement(t *testing.T, conn net.Conn, steps ...managementStep) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for _, step := range steps {
+			if step.hangUp {
+				return
+			}
+			if step.send != "" {
+				if _, err := io.WriteString(conn, step.send); err != nil {
+					return
+				}
+				continue
+			}
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Errorf("management server: expected %q, got %v", step.expect, err)
+				return
+			}
+			if line != step.expect+"\n" {
+				t.Errorf("management server: got %q, want %q", line, step.expect)
+				return
+			}
+		}
+		io.Copy(io.Discard, r)
+	}()
+	return done
+}
+
+// fetchStatus runs m.status against a fake management interface playing `steps`
+func fetchStatus(ctx context.Context, t *testing.T, m *ManagementClient, steps ...managementStep) (*Status, error) {
+	t.Helper()
+	client, server := net.Pipe()
+	done := serveManagement(t, server, steps...)
+	status, err := m.status(ctx, client, nil)
+	client.Close()
+	<-done
+	return status, err
+}
+
+func TestManagementClientHandshake(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		steps    []managementStep
+		wantErr  error
+	}{
+		{
+			name:  "no password",
+			steps: []managementStep{send(managementBanner), expect("status 2"), send(managementStatus), expect("quit")},
+		},
+		{
+			name:     "password not asked",
+			password: "secret",
+			steps:    []managementStep{send(managementBanner), expect("status 2"), send(managementStatus), expect("quit")},
+		},
+		{
+			name:     "correct password",
+			password: "secret",
+			steps: []managementStep{
+				send(passwordPrompt), expect("secret"), send("SUCCESS: password is correct\n"), send(managementBanner),
+				expect("status 2"), send(managementStatus), expect("quit"),
+			},
+		},
+		{
+			name:     "prompt split across writes",
+			password: "secret",
+			steps: []managementStep{
+				send("ENTER "), send("PASSWORD:"), expect("secret"), send("SUCCESS: password is correct\n"), send(managementBanner),
+				expect("status 2"), send(managementStatus), expect("quit"),
+			},
+		},
+		{
+			name:     "greeting shorter than the prompt",
+			password: "secret",
+			steps: []managementStep{
+				send(passwordPrompt), expect("secret"), send("SUCCESS: password is correct\n"), send(">INFO:x\n"),
+				expect("status 2"), send(managementStatus), expect("quit"),
+			},
+		},
+		{
+			name:     "wrong password",
+			password: "wrong",
+			steps:    []managementStep{send(passwordPrompt), expect("wrong"), send("ERROR: bad password\n")},
+			wantErr:  ErrAuthFailed,
+		},
+		{
+			name:     "wrong password and hang up",
+			password: "wrong",
+			steps:    []managementStep{send(passwordPrompt), expect("wrong"), hangUp()},
+			wantErr:  ErrAuthFailed,
+		},
+		{
+			name:    "password required but not given",
+			steps:   []managementStep{send(passwordPrompt)},
+			wantErr: ErrPasswordRequired,
+		},
+		{
+			name: "notifications interleaved in the status reply",
+			steps: []managementStep{
+				send(">HOLD:Waiting for hold release:0\n"), send(managementBanner), expect("status 2"),
+				send(strings.Replace(managementStatus, "HEADER,ROUTING_TABLE", ">BYTECOUNT_CLI:0,100,200\n>INFO:x\nHEADER,ROUTING_TABLE", 1)),
+				expect("quit"),
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			status, err := fetchStatus(ctx, t, &ManagementClient{Password: test.password}, test.steps...)
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("status error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("status: %v", err)
+			}
+			if len(status.Clients) != 1 || status.Clients[0].Name != "alice" || len(status.Routes) != 1 {
+				t.Errorf("got clients %+v and routes %+v, want alice and her route", status.Clients, status.Routes)
+			}
+		})
+	}
+}
+
+func TestManagementClientStatusReply(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int
+		steps   []managementStep
+		wantErr string
+	}{
+		{
+			name:  "stops at END",
+			steps: []managementStep{send(managementBanner), expect("status 2"), send(managementStatus), expect("quit")},
+		},
+		{
+			name: "ignores the output after END",
+			steps: []managementStep{
+				send(managementBanner), expect("status 2"),
+				send(managementStatus + "CLIENT_LIST,mallory,198.51.100.1:1194,10.8.0.9,,1,2,,,UNDEF,9,9,\n"), expect("quit"),
+			},
+		},
+		{
+			name:    "version 3",
+			version: 3,
+			steps: []managementStep{
+				send(managementBanner), expect("status 3"), send(strings.ReplaceAll(managementStatus, ",", "\t")), expect("quit"),
+			},
+		},
+		{
+			name: "skips notifications",
+			steps: []managementStep{
+				send(managementBanner), expect("status 2"),
+				send(">CLIENT:ESTABLISHED,1\n>CLIENT:ENV,common_name=bob\n>CLIENT:ENV,END\n" +
+					strings.Replace(managementStatus, "END\n", ">BYTECOUNT_CLI:0,100,200\nEND\n", 1)),
+				expect("quit"),
+			},
+		},
+		{
+			name:  "CRLF line breaks",
+			steps: []managementStep{send(managementBanner), expect("status 2"), send(strings.ReplaceAll(managementStatus, "\n", "\r\n")), expect("quit")},
+		},
+		{
+			name: "ERROR reply",
+			steps: []managementStep{
+				send(managementBanner), expect("status 2"), send("ERROR: unknown command, enter 'help' for more options\n"),
+			},
+			wantErr: `ovpnstats: management status command failed: "ERROR: unknown command, enter 'help' for more options"`,
+		},
+		{
+			name: "hang up before END",
+			steps: []managementStep{
+				send(managementBanner), expect("status 2"), send(strings.TrimSuffix(managementStatus, "END\n")), hangUp(),
+			},
+			wantErr: io.EOF.Error(),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			status, err := fetchStatus(ctx, t, &ManagementClient{Version: test.version}, test.steps...)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Fatalf("status error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("status: %v", err)
+			}
+			if len(status.Clients) != 1 || status.Clients[0].Name != "alice" || len(status.Routes) != 1 {
+				t.Errorf("got clients %+v and routes %+v, want alice and her route", status.Clients, status.Routes)
+			}
+		})
+	}
+}
+
+func TestManagementClientContextDone(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	// The server sends part of the reply and hangs, until ctx expires and the client gives up
+	_, err := fetchStatus(ctx, t, &ManagementClient{},
+		send(managementBanner), expect("status 2"), send(strings.SplitAfter(managementStatus, "\n")[0]))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("status error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestManagementClientStatus(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("can't listen on TCP: %v", err)
+	}
+	defer listener.Close()
+	var done <-chan struct{}
+	accepted := make(chan struct{})
+	go func() {
+		defer close(accepted)
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		done = serveManagement(t, conn, send(managementBanner), expect("status 2"), send(managementStatus), expect("quit"), hangUp())
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	status, err := (&ManagementClient{Address: listener.Addr().String()}).Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	<-accepted
+	<-done
+	if len(status.Clients) != 1 || status.Clients[0].Name != "alice" {
+		t.Errorf("got clients %+v, want alice", status.Clients)
+	}
+}
@@ -0,0 +1,57 @@
+package ovpnstats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// labelValueEscaper escapes OpenMetrics label values
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteOpenMetrics writes `s` to `w` in the OpenMetrics text exposition format, ready to be served as a /metrics body
+// with the "application/openmetrics-text; version=1.0.0; charset=utf-8" content type.
+// It exposes the server's Title as the version label of an info metric, the number of clients and, per client, the bytes
+// received and sent as counters labeled by common_name and client_id, so that sessions sharing a Common Name stay distinct
+func WriteOpenMetrics(w io.Writer, s *Status) error {
+	bw := bufio.NewWriter(w)
+
+	if s != nil && s.Title != "" {
+		fmt.Fprintln(bw, "# TYPE openvpn_server info")
+		fmt.Fprintln(bw, "# HELP openvpn_server OpenVPN server version.")
+		fmt.Fprintf(bw, "openvpn_server_info{version=\"%s\"} 1\n", labelValueEscaper.Replace(s.Title))
+	}
+
+	fmt.Fprintln(bw, "# TYPE openvpn_clients gauge")
+	fmt.Fprintln(bw, "# HELP openvpn_clients Number of connected clients.")
+	fmt.Fprintf(bw, "openvpn_clients %d\n", s.NumClients())
+
+	families := []struct {
+		name, help string
+		value      func(ClientInfo) int64
+	}{
+		{"openvpn_client_received_bytes", "Bytes received from the client.", func(c ClientInfo) int64 { return c.BytesReceived }},
+		{"openvpn_client_sent_bytes", "Bytes sent to the client.", func(c ClientInfo) int64 { return c.BytesSent }},
+	}
+	for _, family := range families {
+		fmt.Fprintf(bw, "# TYPE %s counter\n", family.name)
+		fmt.Fprintf(bw, "# UNIT %s bytes\n", family.name)
+		fmt.Fprintf(bw, "# HELP %s %s\n", family.name, family.help)
+		if s == nil {
+			continue
+		}
+		for _, client := range s.Clients {
+			fmt.Fprintf(bw, "%s_total{common_name=\"%s\",client_id=\"%s\"} %d\n",
+				family.name,
+				labelValueEscaper.Replace(client.Name),
+				strconv.Itoa(client.ClientID),
+				family.value(client),
+			)
+		}
+	}
+
+	fmt.Fprintln(bw, "# EOF")
+	return bw.Flush()
+}
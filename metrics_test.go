@@ -0,0 +1,42 @@
+package ovpnstats_test
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+func TestWriteOpenMetrics(t *testing.T) {
+	want, err := os.ReadFile("testdata/openmetrics.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := &ovpnstats.Status{
+		Title: "OpenVPN 2.6.8 \"patched\" \\ build\nline",
+		Clients: []ovpnstats.ClientInfo{
+			{Name: "alice", ClientID: 0, BytesReceived: 100, BytesSent: 200},
+			{Name: `bob "the builder"`, ClientID: 1, BytesReceived: 300, BytesSent: 400},
+			{Name: "C:\\carol\nx", ClientID: 2},
+		},
+	}
+	var b bytes.Buffer
+	if err := ovpnstats.WriteOpenMetrics(&b, status); err != nil {
+		t.Fatalf("WriteOpenMetrics: %v", err)
+	}
+	if b.String() != string(want) {
+		t.Errorf("WriteOpenMetrics wrote:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestWriteOpenMetricsNilStatus(t *testing.T) {
+	var b bytes.Buffer
+	if err := ovpnstats.WriteOpenMetrics(&b, nil); err != nil {
+		t.Fatalf("WriteOpenMetrics: %v", err)
+	}
+	if !strings.HasSuffix(b.String(), "\n# EOF\n") || !strings.Contains(b.String(), "\nopenvpn_clients 0\n") || strings.Contains(b.String(), "openvpn_server") {
+		t.Errorf("WriteOpenMetrics(nil) wrote %q, want no clients nor server info, ending with # EOF", b.String())
+	}
+}
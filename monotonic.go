@@ -0,0 +1,62 @@
+package ovpnstats
+
+import "time"
+
+// Anomaly is a session whose byte counters decreased between two snapshots without reconnecting, or a snapshot
+// written before the previous one
+type Anomaly struct {
+	// CommonName and ClientID are the session's, empty for a snapshot written before the previous one
+	CommonName string
+	ClientID   int
+	// Previous and Current are the indices, in the snapshots given to CheckMonotonic, of the snapshots compared
+	Previous int
+	Current  int
+	// RxDecrease and TxDecrease are how much Bytes Received and Bytes Sent decreased, 0 when they didn't
+	RxDecrease int64
+	TxDecrease int64
+	// TimeDecrease is how much UpdatedAt went backwards, pointing to an unordered archive or a clock bug, 0 when it
+	// didn't or either time is unknown
+	TimeDecrease time.Duration
+}
+
+// CheckMonotonic returns the sessions whose Bytes Received or Bytes Sent decreased from each snapshot of `snapshots`
+// to the next one, in order, pointing to a corrupted archive or a counter bug. Sessions are matched by Common Name,
+// Client ID and Connected Since, so counters starting again from zero after a reconnection, which changes
+// Connected Since, are a new session and not reported. A snapshot whose UpdatedAt is before the previous one's is
+// reported too, before the sessions of that pair. Nil snapshots are skipped
+func CheckMonotonic(snapshots []*Status) []Anomaly {
+	var anomalies []Anomaly
+	previous := -1
+	for current, s := range snapshots {
+		if s == nil {
+			continue
+		}
+		if previous >= 0 {
+			if back := snapshots[previous].UpdatedAt.Sub(s.UpdatedAt); back > 0 && !s.UpdatedAt.IsZero() {
+				anomalies = append(anomalies, Anomaly{Previous: previous, Current: current, TimeDecrease: back})
+			}
+			for _, change := range Diff(snapshots[previous], s).Remaining {
+				rx := change.Previous.BytesReceived - change.Current.BytesReceived
+				tx := change.Previous.BytesSent - change.Current.BytesSent
+				if rx <= 0 && tx <= 0 {
+					continue
+				}
+				anomaly := Anomaly{
+					CommonName: change.Current.Name,
+					ClientID:   change.Current.ClientID,
+					Previous:   previous,
+					Current:    current,
+				}
+				if rx > 0 {
+					anomaly.RxDecrease = rx
+				}
+				if tx > 0 {
+					anomaly.TxDecrease = tx
+				}
+				anomalies = append(anomalies, anomaly)
+			}
+		}
+		previous = current
+	}
+	return anomalies
+}
@@ -0,0 +1,73 @@
+package ovpnstats_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+func TestCheckMonotonic(t *testing.T) {
+	alice := traffic("alice", 1000, 2000)
+	aliceGrown := traffic("alice", 1500, 2500)
+	aliceRxBack := traffic("alice", 400, 2500)
+	aliceBothBack := traffic("alice", 100, 200)
+	aliceReconnected := session("alice", 3, 0, 1614592800)
+	bob := traffic("bob", 50, 60)
+	bob.ClientID = 1
+	tests := []struct {
+		name      string
+		snapshots []*ovpnstats.Status
+		want      []ovpnstats.Anomaly
+	}{
+		{name: "empty"},
+		{name: "growth", snapshots: []*ovpnstats.Status{snapshot(1614592800, alice), snapshot(1614592860, aliceGrown, bob)}},
+		{
+			name:      "Bytes Received decreased",
+			snapshots: []*ovpnstats.Status{snapshot(1614592800, alice, bob), snapshot(1614592860, aliceRxBack, bob)},
+			want:      []ovpnstats.Anomaly{{CommonName: "alice", Previous: 0, Current: 1, RxDecrease: 600}},
+		},
+		{
+			name:      "both counters decreased",
+			snapshots: []*ovpnstats.Status{snapshot(1614592800, alice), snapshot(1614592860, aliceBothBack)},
+			want:      []ovpnstats.Anomaly{{CommonName: "alice", Previous: 0, Current: 1, RxDecrease: 900, TxDecrease: 1800}},
+		},
+		{
+			name:      "reconnection starts again from zero",
+			snapshots: []*ovpnstats.Status{snapshot(1614592740, alice), snapshot(1614592800, aliceReconnected)},
+		},
+		{
+			name:      "new session",
+			snapshots: []*ovpnstats.Status{snapshot(1614592800, alice), snapshot(1614592860, alice, bob)},
+		},
+		{
+			name:      "decrease across a nil snapshot",
+			snapshots: []*ovpnstats.Status{snapshot(1614592800, alice), nil, snapshot(1614592920, aliceBothBack)},
+			want:      []ovpnstats.Anomaly{{CommonName: "alice", Previous: 0, Current: 2, RxDecrease: 900, TxDecrease: 1800}},
+		},
+		{
+			name:      "only consecutive snapshots compared",
+			snapshots: []*ovpnstats.Status{snapshot(1614592800, alice), snapshot(1614592860, bob), snapshot(1614592920, aliceBothBack)},
+		},
+		{
+			name:      "UpdatedAt went backwards",
+			snapshots: []*ovpnstats.Status{snapshot(1614592860, aliceGrown), snapshot(1614592800, alice)},
+			want: []ovpnstats.Anomaly{
+				{Previous: 0, Current: 1, TimeDecrease: time.Minute},
+				{CommonName: "alice", Previous: 0, Current: 1, RxDecrease: 500, TxDecrease: 500},
+			},
+		},
+		{
+			name:      "unknown UpdatedAt",
+			snapshots: []*ovpnstats.Status{snapshot(1614592860, alice), {Clients: []ovpnstats.ClientInfo{aliceGrown}}, snapshot(1614592800, aliceGrown)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ovpnstats.CheckMonotonic(test.snapshots); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("CheckMonotonic() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
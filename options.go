@@ -0,0 +1,287 @@
+package ovpnstats
+
+import (
+	"context"
+	"time"
+)
+
+// Option configures how a status file is parsed
+type Option func(*options)
+
+// Logger receives the lines skipped while parsing. A *log.Logger satisfies it
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Printf(string, ...interface{}) {}
+
+type options struct {
+	clientFilter func(ClientInfo) bool
+	routeFilter  func(RoutingInfo) bool
+	// connectedAfter drops the clients connected at or before it, unless zero
+	connectedAfter time.Time
+	logger         Logger
+	// maxBytes is the maximum size of the input, unless 0
+	maxBytes int64
+	ctx      context.Context
+	// strictEnd requires an END line
+	strictEnd bool
+	// concurrency is the number of workers parsing records
+	concurrency int
+	// timeLayouts are the layouts of human readable times
+	timeLayouts []string
+	// noSymlinks refuses status files which are symbolic links
+	noSymlinks bool
+	// expectedHeaders are the expected HEADER columns keyed by record type
+	expectedHeaders map[string][]string
+	// atomicRead reads status files whole before parsing them, retrying truncated ones
+	atomicRead bool
+	// validateUTF8 rejects lines which aren't valid UTF-8
+	validateUTF8 bool
+	// numberGrouping is the thousands separator removed from numeric CLIENT_LIST columns, if not 0
+	numberGrouping rune
+	// nonPositiveTimeUnknown parses times at or before the epoch as unknown
+	nonPositiveTimeUnknown bool
+	// strings interns the string fields of the records kept, when not nil
+	strings *StringTable
+	// stopAtEnd reads *bufio.Reader inputs line by line, so they aren't read past END
+	stopAtEnd bool
+	// lenient skips invalid CLIENT_LIST and ROUTING_TABLE records, recording a warning
+	lenient bool
+	// resolveVirtualFromRoutes fills empty client Virtual Addresses from their routes
+	resolveVirtualFromRoutes bool
+	// clock is set as the Status.Clock
+	clock Clock
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{logger: nopLogger{}, timeLayouts: defaultTimeLayouts}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithClientFilter only collects the CLIENT_LIST entries for which keep returns true.
+// Rejected entries are dropped while parsing and never accumulated
+func WithClientFilter(keep func(ClientInfo) bool) Option {
+	return func(o *options) {
+		o.clientFilter = keep
+	}
+}
+
+// WithRouteFilter only collects the ROUTING_TABLE entries for which keep returns true.
+// Rejected entries are dropped while parsing and never accumulated
+func WithRouteFilter(keep func(RoutingInfo) bool) Option {
+	return func(o *options) {
+		o.routeFilter = keep
+	}
+}
+
+// WithConnectedAfter only collects the CLIENT_LIST entries connected after `t`, dropping those connected at or before it.
+// ROUTING_TABLE entries have no connection time and are not affected.
+// It can be combined with WithClientFilter, entries must pass both to be collected
+func WithConnectedAfter(t time.Time) Option {
+	return func(o *options) {
+		o.connectedAfter = t
+	}
+}
+
+// WithLogger reports every skipped line (blank, comment or unknown record type) to `logger`, with its line number.
+// The parse result is not affected. Skipped lines are not reported by default
+func WithLogger(logger Logger) Option {
+	return func(o *options) {
+		if logger == nil {
+			logger = nopLogger{}
+		}
+		o.logger = logger
+	}
+}
+
+// WithMaxBytes makes parsing fail with an error wrapping ErrInputTooLarge as soon as more than `n` bytes are read,
+// or, for StreamStatusBlocks, as soon as a status block exceeds them.
+// Combined with WithContext it bounds the resources spent on untrusted input
+func WithMaxBytes(n int64) Option {
+	return func(o *options) {
+		o.maxBytes = n
+	}
+}
+
+// WithContext makes parsing fail with ctx.Err() once `ctx` is done.
+// It's checked before every read, so a slow input can't hold the parser past ctx's deadline between reads,
+// but a single read blocked in the underlying reader can't be interrupted
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithStrictEnd makes parsing fail with ErrMissingEnd when the input has no END line, e.g. because its writer was
+// interrupted. The records read are still parsed normally, even when the last one has no line break
+func WithStrictEnd() Option {
+	return func(o *options) {
+		o.strictEnd = true
+	}
+}
+
+// WithConcurrency parses the CLIENT_LIST and ROUTING_TABLE records with `n` concurrent workers.
+// The whole input is read first, then its records are split in chunks, whose lines are split into fields and parsed
+// in parallel, and merged back in order, so the result, including the error returned for an invalid input,
+// is the same as a sequential parse.
+// Filters and loggers are still called sequentially. Inputs with few records are parsed sequentially,
+// as the workers wouldn't pay off
+func WithConcurrency(n int) Option {
+	return func(o *options) {
+		o.concurrency = n
+	}
+}
+
+// WithTimeLayouts adds `layouts` (as in time.Parse) to the ones tried to parse human readable times, which are only used
+// when the corresponding time_t column is missing or invalid. They are tried after the standard OpenVPN layouts,
+// "2006-01-02 15:04:05" and the ctime style "Mon Jan _2 15:04:05 2006", and parsed in local time
+func WithTimeLayouts(layouts []string) Option {
+	return func(o *options) {
+		o.timeLayouts = append(append([]string(nil), o.timeLayouts...), layouts...)
+	}
+}
+
+// WithNoSymlinks makes ParseStatusFile fail with ErrSymlink when the status file is a symbolic link, so a less trusted
+// process able to write the file's directory can't point a privileged parser at another file.
+// The path is checked with Lstat, then the opened file must be the same one Lstat found (same device and inode),
+// so swapping the path for a symlink between both steps is detected too.
+// Only the last component of the path is checked: symlinks in its parent directories are followed.
+// ParseStatus ignores it
+func WithNoSymlinks() Option {
+	return func(o *options) {
+		o.noSymlinks = true
+	}
+}
+
+// WithAtomicRead makes ParseStatusFile read the whole status file into memory before parsing it, instead of scanning it
+// while OpenVPN may be rewriting it. OpenVPN doesn't replace the file atomically but truncates and rewrites it in place,
+// so a read without END line is retried a few times, shortly after, and the last read is parsed anyway
+// (combine it with WithStrictEnd to fail on it). WithMaxBytes bounds the memory used.
+// ParseStatus ignores it
+func WithAtomicRead() Option {
+	return func(o *options) {
+		o.atomicRead = true
+	}
+}
+
+// WithValidateUTF8 makes parsing fail with ErrInvalidUTF8 on a line which isn't valid UTF-8, e.g. a Common Name taken
+// from a certificate in another encoding. Otherwise fields are kept as the raw bytes read, valid UTF-8 or not
+func WithValidateUTF8() Option {
+	return func(o *options) {
+		o.validateUTF8 = true
+	}
+}
+
+// WithNumberGrouping removes the thousands separator `sep` from the numeric CLIENT_LIST columns (Bytes Received,
+// Bytes Sent, Client ID and Peer ID) before parsing them, for files rewritten with localized numbers like "1.234.567".
+// It can't fix a separator which is also the field separator, like "," in comma separated version 2 files,
+// as the grouped numbers are already split across several fields
+func WithNumberGrouping(sep rune) Option {
+	return func(o *options) {
+		o.numberGrouping = sep
+	}
+}
+
+// WithNonPositiveTimeUnknown parses the times at or before the epoch, like an uninitialized time_t of 0, as unknown:
+// the zero time.Time, see ClientInfo.HasConnectedSince and RoutingInfo.HasLastRef. It applies to Connected Since,
+// Last Ref and TIME. Otherwise these are kept as is, e.g. 1970-01-01 for a time_t of 0
+func WithNonPositiveTimeUnknown() Option {
+	return func(o *options) {
+		o.nonPositiveTimeUnknown = true
+	}
+}
+
+// WithStringInterning stores the string fields of the clients and routes kept (names, addresses, ciphers, Extra
+// columns...) in `table`, so that equal strings share their memory, both within a snapshot and across all the
+// snapshots parsed with the same table. It trades a slightly slower parse, a map lookup per field, for less memory
+// when keeping many snapshots of the same user base. A nil `table` interns within the parse only
+func WithStringInterning(table *StringTable) Option {
+	return func(o *options) {
+		if table == nil {
+			table = NewStringTable()
+		}
+		o.strings = table
+	}
+}
+
+// WithStopAtEnd guarantees that when the input is a *bufio.Reader nothing past the first END line is read from it,
+// where parsing always stops, so the caller can go on reading whatever follows the status, e.g. in a multiplexed
+// protocol stream. The input is then read from the bufio.Reader line by line, still within WithMaxBytes and WithContext.
+// Other readers are read in chunks and may be consumed past END: wrap them with bufio.NewReader to keep reading
+// them afterwards
+func WithStopAtEnd() Option {
+	return func(o *options) {
+		o.stopAtEnd = true
+	}
+}
+
+// WithLenient skips the CLIENT_LIST and ROUTING_TABLE records which fail to parse, e.g. with a corrupt number,
+// adding a ParseWarning to Status.Warnings for each of them, instead of failing the whole parse.
+// Other errors, like malformed TIME lines or the limits of other options, still fail it
+func WithLenient() Option {
+	return func(o *options) {
+		o.lenient = true
+	}
+}
+
+// WithResolveVirtualFromRoutes fills, once both sections are parsed, the empty Virtual Address of each client with its
+// route to a single IPv4 address, as the routing table is authoritative for address assignment and the client list may
+// show none yet. Routes are correlated like Status.RoutesFor does, by Common Name and Real Address, as they have no
+// Client ID: sessions sharing both (duplicate-cn behind the same NAT and port) are ambiguous and left untouched.
+// ParseStatusStream ignores it
+func WithResolveVirtualFromRoutes() Option {
+	return func(o *options) {
+		o.resolveVirtualFromRoutes = true
+	}
+}
+
+// WithClock sets `clock` as the Status.Clock of the parsed Status, the time source of its helpers needing the current
+// time, e.g. a fixed clock for tests
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// knownTime returns `t`, or the zero time.Time when it's unknown according to WithNonPositiveTimeUnknown
+func (o *options) knownTime(t time.Time) time.Time {
+	if o.nonPositiveTimeUnknown && t.Unix() <= 0 {
+		return time.Time{}
+	}
+	return t
+}
+
+// keepClient reports whether `client` passes the client filters
+func (o *options) keepClient(client ClientInfo) bool {
+	if !o.connectedAfter.IsZero() && !client.ConnectedSince.After(o.connectedAfter) {
+		return false
+	}
+	return o.clientFilter == nil || o.clientFilter(client)
+}
+
+// keepRoute reports whether `route` passes the route filters
+func (o *options) keepRoute(route RoutingInfo) bool {
+	return o.routeFilter == nil || o.routeFilter(route)
+}
+
+// WithExpectedHeader makes parsing fail when a HEADER line doesn't match `expected`.
+// expected holds the HEADER line fields after "HEADER", starting with the record type it describes, e.g.
+// {"CLIENT_LIST", "Common Name", "Real Address", ...}. HEADER lines of other record types aren't checked.
+// It can be given once per record type to validate several sections
+func WithExpectedHeader(expected []string) Option {
+	return func(o *options) {
+		if len(expected) == 0 {
+			return
+		}
+		if o.expectedHeaders == nil {
+			o.expectedHeaders = make(map[string][]string)
+		}
+		o.expectedHeaders[expected[0]] = expected[1:]
+	}
+}
@@ -0,0 +1,142 @@
+// Package ovpnstatstest provides helpers to test code using ovpnstats
+package ovpnstatstest
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+// AssertStatusEqual fails `t` when `got` differs from `want`, reporting the first differing field.
+// Clients and routes are compared regardless of their order, times with time.Time.Equal
+// and nil maps equal empty ones. Warnings are compared in order, by line number and error message
+func AssertStatusEqual(t testing.TB, want, got *ovpnstats.Status) {
+	t.Helper()
+	if difference := diffStatus(want, got); difference != "" {
+		t.Errorf("ovpnstats.Status differs: %s", difference)
+	}
+}
+
+func diffStatus(want, got *ovpnstats.Status) string {
+	if want == nil || got == nil {
+		if want != got {
+			return fmt.Sprintf("got %v, want %v", got, want)
+		}
+		return ""
+	}
+	if difference := diffFields("Status", reflect.ValueOf(*want), reflect.ValueOf(*got)); difference != "" {
+		return difference
+	}
+
+	if len(got.Clients) != len(want.Clients) {
+		return fmt.Sprintf("got %d Clients, want %d", len(got.Clients), len(want.Clients))
+	}
+	wantClients, gotClients := sortedClients(want.Clients), sortedClients(got.Clients)
+	for i := range wantClients {
+		if wantClients[i].Equal(gotClients[i]) {
+			continue
+		}
+		path := fmt.Sprintf("Clients[%q]", wantClients[i].Name)
+		if difference := diffFields(path, reflect.ValueOf(wantClients[i]), reflect.ValueOf(gotClients[i])); difference != "" {
+			return difference
+		}
+	}
+
+	if len(got.Routes) != len(want.Routes) {
+		return fmt.Sprintf("got %d Routes, want %d", len(got.Routes), len(want.Routes))
+	}
+	wantRoutes, gotRoutes := sortedRoutes(want.Routes), sortedRoutes(got.Routes)
+	for i := range wantRoutes {
+		if wantRoutes[i].Equal(gotRoutes[i]) {
+			continue
+		}
+		path := fmt.Sprintf("Routes[%q]", wantRoutes[i].VirtualAddress)
+		if difference := diffFields(path, reflect.ValueOf(wantRoutes[i]), reflect.ValueOf(gotRoutes[i])); difference != "" {
+			return difference
+		}
+	}
+
+	if len(got.Warnings) != len(want.Warnings) {
+		return fmt.Sprintf("got %d Warnings, want %d", len(got.Warnings), len(want.Warnings))
+	}
+	for i, w := range want.Warnings {
+		if g := got.Warnings[i]; g.Line != w.Line || errorString(g.Err) != errorString(w.Err) {
+			return fmt.Sprintf("Warnings[%d]: got %q, want %q", i, g.Error(), w.Error())
+		}
+	}
+	return ""
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// diffFields compares the exported fields of the structs `want` and `got`, except slices of structs which are compared apart
+func diffFields(path string, want, got reflect.Value) string {
+	timeType := reflect.TypeOf(time.Time{})
+	for i := 0; i < want.NumField(); i++ {
+		field := want.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		w, g := want.Field(i), got.Field(i)
+		switch {
+		case field.Type == timeType:
+			if !w.Interface().(time.Time).Equal(g.Interface().(time.Time)) {
+				return fmt.Sprintf("%s.%s: got %v, want %v", path, field.Name, g.Interface(), w.Interface())
+			}
+		case field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct:
+			continue
+		case (field.Type.Kind() == reflect.Map || field.Type.Kind() == reflect.Slice) && w.Len() == 0 && g.Len() == 0:
+			continue
+		default:
+			if !reflect.DeepEqual(w.Interface(), g.Interface()) {
+				return fmt.Sprintf("%s.%s: got %#v, want %#v", path, field.Name, g.Interface(), w.Interface())
+			}
+		}
+	}
+	return ""
+}
+
+func sortedClients(clients []ovpnstats.ClientInfo) []ovpnstats.ClientInfo {
+	sorted := append([]ovpnstats.ClientInfo(nil), clients...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		switch {
+		case a.Name != b.Name:
+			return a.Name < b.Name
+		case a.ClientID != b.ClientID:
+			return a.ClientID < b.ClientID
+		case !a.ConnectedSince.Equal(b.ConnectedSince):
+			return a.ConnectedSince.Before(b.ConnectedSince)
+		default:
+			return a.RealAddress < b.RealAddress
+		}
+	})
+	return sorted
+}
+
+func sortedRoutes(routes []ovpnstats.RoutingInfo) []ovpnstats.RoutingInfo {
+	sorted := append([]ovpnstats.RoutingInfo(nil), routes...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		switch {
+		case a.VirtualAddress != b.VirtualAddress:
+			return a.VirtualAddress < b.VirtualAddress
+		case a.CommonName != b.CommonName:
+			return a.CommonName < b.CommonName
+		case a.RealAddress != b.RealAddress:
+			return a.RealAddress < b.RealAddress
+		default:
+			return a.LastRef.Before(b.LastRef)
+		}
+	})
+	return sorted
+}
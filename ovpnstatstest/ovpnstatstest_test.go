@@ -0,0 +1,73 @@
+package ovpnstatstest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+func TestDiffStatus(t *testing.T) {
+	alice := ovpnstats.ClientInfo{Name: "alice", ConnectedSince: time.Unix(1614589200, 0)}
+	bob := ovpnstats.ClientInfo{Name: "bob", ConnectedSince: time.Unix(1614591000, 0)}
+	warning := ovpnstats.ParseWarning{Line: 3, Err: errors.New("ovpnstats: invalid Bytes Sent \"x\"")}
+	tests := []struct {
+		name     string
+		want     *ovpnstats.Status
+		got      *ovpnstats.Status
+		wantDiff string
+	}{
+		{
+			name: "clients in another order",
+			want: &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{alice, bob}},
+			got:  &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{bob, alice}},
+		},
+		{
+			name: "times in another location",
+			want: &ovpnstats.Status{UpdatedAt: time.Unix(1614592800, 0)},
+			got:  &ovpnstats.Status{UpdatedAt: time.Unix(1614592800, 0).UTC()},
+		},
+		{
+			name:     "different client",
+			want:     &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{alice}},
+			got:      &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{{Name: "alice"}}},
+			wantDiff: `Clients["alice"].ConnectedSince`,
+		},
+		{
+			name: "equal warnings",
+			want: &ovpnstats.Status{Warnings: []ovpnstats.ParseWarning{warning}},
+			got:  &ovpnstats.Status{Warnings: []ovpnstats.ParseWarning{{Line: 3, Err: errors.New(warning.Err.Error())}}},
+		},
+		{
+			name:     "missing warning",
+			want:     &ovpnstats.Status{Warnings: []ovpnstats.ParseWarning{warning}},
+			got:      &ovpnstats.Status{},
+			wantDiff: "got 0 Warnings, want 1",
+		},
+		{
+			name:     "warning on another line",
+			want:     &ovpnstats.Status{Warnings: []ovpnstats.ParseWarning{warning}},
+			got:      &ovpnstats.Status{Warnings: []ovpnstats.ParseWarning{{Line: 4, Err: warning.Err}}},
+			wantDiff: "Warnings[0]",
+		},
+		{
+			name:     "warning with another error",
+			want:     &ovpnstats.Status{Warnings: []ovpnstats.ParseWarning{warning}},
+			got:      &ovpnstats.Status{Warnings: []ovpnstats.ParseWarning{{Line: 3, Err: errors.New("ovpnstats: invalid Client ID")}}},
+			wantDiff: "Warnings[0]",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diff := diffStatus(test.want, test.got)
+			if test.wantDiff == "" && diff != "" {
+				t.Errorf("diffStatus = %q, want no difference", diff)
+			}
+			if test.wantDiff != "" && !strings.HasPrefix(diff, test.wantDiff) {
+				t.Errorf("diffStatus = %q, want a difference starting with %q", diff, test.wantDiff)
+			}
+		})
+	}
+}
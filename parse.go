@@ -3,14 +3,30 @@ package ovpnstats
 
 import (
 	"bufio"
-	"os"
+	"errors"
+	"fmt"
+	"io"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 const splitCharacter = ","
 
+// splitCharacterV3 separates the fields of version 3 status files
+const splitCharacterV3 = "\t"
+
+// humanTimeLayout is the layout of the human readable "Connected Since", "Last Ref" and TIME columns of version 2 and 3 files,
+// in the server's local time. It's only used when the corresponding time_t column is empty or invalid
+const humanTimeLayout = "2006-01-02 15:04:05"
+
+// ctimeLayout is the ctime(3) style layout of the human readable times of legacy files, on both Linux and Windows
+const ctimeLayout = "Mon Jan _2 15:04:05 2006"
+
+// defaultTimeLayouts are the layouts tried, in order, to parse human readable times
+var defaultTimeLayouts = []string{humanTimeLayout, ctimeLayout}
+
 // ClientInfo represents a CLIENT_LIST entry
 // HEADER,CLIENT_LIST,Common Name,Real Address,Virtual Address,Virtual IPv6 Address,Bytes Received,Bytes Sent,Connected Since,Connected Since (time_t),Username,Client ID,Peer ID,Data Channel Cipher
 // 0. HEADER
@@ -27,18 +43,32 @@ const splitCharacter = ","
 //10. Client ID
 //11. Peer ID
 //12. Data Channel Cipher
+// Columns are mapped by name from the HEADER line, so older layouts like OpenVPN 2.4's, which ends at Peer ID, leave the
+// fields of their missing columns zero valued. Without HEADER, the columns above are assumed, of which only the trailing
+// Data Channel Cipher may be missing, like in 2.4 files. A record with fewer fields than its layout, e.g. cut by a
+// truncated write, fails to parse (see WithLenient)
 type ClientInfo struct {
-	Name              string
-	RealAddress       string
-	VirtualAddress    string
-	VirtualV6Address  string
-	BytesReceived     int
-	BytesSent         int
+	Name             string
+	RealAddress      string
+	VirtualAddress   string
+	VirtualV6Address string
+	// BytesReceived and BytesSent are the session's traffic counters. Values beyond the int64 range, like the wrapped
+	// unsigned counters some platforms print, saturate to math.MaxInt64 instead of failing the record
+	BytesReceived     int64
+	BytesSent         int64
 	ConnectedSince    time.Time
 	Username          string
 	ClientID          int
 	PeerID            int
 	DataChannelCipher string
+	// Protocol is the client's transport, "udp" or "tcp", when the status shows it either in a "Protocol" column or
+	// as a prefix of the Real Address (e.g. "udp4:203.0.113.5:1194"). It's empty otherwise, never guessed
+	Protocol string
+	// Extra holds the columns of the CLIENT_LIST HEADER not modeled above, keyed by column name
+	Extra map[string]string
+	// Metadata holds annotations attached to the client by the application, e.g. after an enrichment.
+	// OpenVPN never writes them: they're only read from, and written with WithMetadata to, "meta:" prefixed columns
+	Metadata map[string]string
 }
 
 // RoutingInfo represents a ROUTING_TABLE entry
@@ -50,93 +80,507 @@ type RoutingInfo struct {
 	LastRef        time.Time
 }
 
-func parseClientListEntry(line string) (ClientInfo, error) {
-	parts := strings.Split(line, splitCharacter)
-	bytesReceived, err := strconv.Atoi(parts[5])
-	if err != nil {
+// The CLIENT_LIST and ROUTING_TABLE HEADER column names, after the record type, written by each supported version,
+// e.g. to be compared with the columns of a file or given to WithExpectedHeader. The parser uses them to lay out
+// records without HEADER, and WriteStatus writes them. They must not be modified
+var (
+	// ClientListColumnsV2 are the CLIENT_LIST columns of version 2 files written by OpenVPN 2.5 and later
+	ClientListColumnsV2 = []string{
+		"Common Name", "Real Address", "Virtual Address", "Virtual IPv6 Address",
+		"Bytes Received", "Bytes Sent", "Connected Since", "Connected Since (time_t)",
+		"Username", "Client ID", "Peer ID", "Data Channel Cipher",
+	}
+	// ClientListColumnsV3 are the CLIENT_LIST columns of version 3 files, the same as version 2 ones
+	ClientListColumnsV3 = ClientListColumnsV2
+	// ClientListColumnsV24 are the CLIENT_LIST columns of OpenVPN 2.4, which has no Data Channel Cipher
+	ClientListColumnsV24 = ClientListColumnsV2[:11:11]
+	// RoutingTableColumns are the ROUTING_TABLE columns of every version
+	RoutingTableColumns = []string{
+		"Virtual Address", "Common Name", "Real Address", "Last Ref", "Last Ref (time_t)",
+	}
+)
+
+// clientListColumns returns the CLIENT_LIST columns of the status file `version`
+func clientListColumns(version int) []string {
+	if version == 3 {
+		return ClientListColumnsV3
+	}
+	return ClientListColumnsV2
+}
+
+// numericClientListColumns are the CLIENT_LIST columns holding numbers, ungrouped by WithNumberGrouping
+var numericClientListColumns = []string{"Bytes Received", "Bytes Sent", "Client ID", "Peer ID"}
+
+// knownClientListColumns are the CLIENT_LIST columns modeled by ClientInfo
+var knownClientListColumns = func() map[string]bool {
+	known := make(map[string]bool, len(ClientListColumnsV2)+1)
+	for _, name := range ClientListColumnsV2 {
+		known[name] = true
+	}
+	known["Protocol"] = true
+	return known
+}()
+
+// parseTimestamp parses the time_t column `unix`, falling back to the human readable column `human` when it's empty or invalid.
+// `human` is parsed in local time with the first of `layouts` matching it. Both empty are an unknown time, the zero time.Time
+func parseTimestamp(unix, human string, layouts []string) (time.Time, error) {
+	if unix == "" && human == "" {
+		return time.Time{}, nil
+	}
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, human, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("ovpnstats: unparseable time %q (time_t %q)", human, unix)
+}
+
+// parseClientListEntry parses the fields of a CLIENT_LIST line laid out as `l`.
+// Columns missing from the layout are left zero valued, while a line missing columns of the layout fails
+func parseClientListEntry(parts []string, l *layout, timeLayouts []string) (ClientInfo, error) {
+	if err := l.check("CLIENT_LIST", parts); err != nil {
 		return ClientInfo{}, err
 	}
-	bytesSent, err := strconv.Atoi(parts[6])
+	bytesReceived, err := l.bytes(parts, "Bytes Received")
 	if err != nil {
 		return ClientInfo{}, err
 	}
-	connectedSinceUnix, err := strconv.Atoi(parts[8])
+	bytesSent, err := l.bytes(parts, "Bytes Sent")
 	if err != nil {
 		return ClientInfo{}, err
 	}
-	clientID, err := strconv.Atoi(parts[10])
+	var connectedSince time.Time
+	connectedSinceUnix, hasUnix := l.field(parts, "Connected Since (time_t)")
+	connectedSinceHuman, hasHuman := l.field(parts, "Connected Since")
+	if hasUnix || hasHuman {
+		connectedSince, err = parseTimestamp(connectedSinceUnix, connectedSinceHuman, timeLayouts)
+		if err != nil {
+			return ClientInfo{}, err
+		}
+	}
+	clientID, err := l.int(parts, "Client ID")
 	if err != nil {
 		return ClientInfo{}, err
 	}
-	peerID, err := strconv.Atoi(parts[11])
+	peerID, err := l.int(parts, "Peer ID")
 	if err != nil {
 		return ClientInfo{}, err
 	}
 	info := ClientInfo{
-		Name:              parts[1],
-		RealAddress:       parts[2],
-		VirtualAddress:    parts[3],
-		VirtualV6Address:  parts[4],
+		Name:              l.string(parts, "Common Name"),
+		RealAddress:       l.string(parts, "Real Address"),
+		VirtualAddress:    l.string(parts, "Virtual Address"),
+		VirtualV6Address:  l.string(parts, "Virtual IPv6 Address"),
 		BytesReceived:     bytesReceived,
 		BytesSent:         bytesSent,
-		ConnectedSince:    time.Unix(int64(connectedSinceUnix), 0),
-		Username:          parts[9],
+		ConnectedSince:    connectedSince,
+		Username:          l.string(parts, "Username"),
 		ClientID:          clientID,
 		PeerID:            peerID,
-		DataChannelCipher: parts[12],
+		DataChannelCipher: l.string(parts, "Data Channel Cipher"),
+		Extra:             l.extra(parts, knownClientListColumns),
+		Metadata:          l.metadata(parts),
+	}
+	if protocol, ok := l.field(parts, "Protocol"); ok {
+		info.Protocol = normalizeProtocol(protocol)
+	} else {
+		info.Protocol, _ = splitProtocol(info.RealAddress)
 	}
 	return info, nil
 }
 
-func parseRoutingTableEntry(line string) (RoutingInfo, error) {
-	parts := strings.Split(line, splitCharacter)
-	lastRefUnix, err := strconv.Atoi(parts[5])
-	if err != nil {
+// normalizeProtocol returns "udp" or "tcp" for a protocol as printed by OpenVPN (e.g. "UDPv4", "tcp6-server"), or "" for others
+func normalizeProtocol(protocol string) string {
+	protocol = strings.ToLower(protocol)
+	for _, p := range []string{"udp", "tcp"} {
+		if strings.HasPrefix(protocol, p) {
+			return p
+		}
+	}
+	return ""
+}
+
+// parseRoutingTableEntry parses the fields of a ROUTING_TABLE line laid out as `l`.
+// Columns missing from the layout are left zero valued, while a line missing columns of the layout fails
+func parseRoutingTableEntry(parts []string, l *layout, timeLayouts []string) (RoutingInfo, error) {
+	if err := l.check("ROUTING_TABLE", parts); err != nil {
 		return RoutingInfo{}, err
 	}
+	var lastRef time.Time
+	lastRefUnix, hasUnix := l.field(parts, "Last Ref (time_t)")
+	lastRefHuman, hasHuman := l.field(parts, "Last Ref")
+	if hasUnix || hasHuman {
+		var err error
+		lastRef, err = parseTimestamp(lastRefUnix, lastRefHuman, timeLayouts)
+		if err != nil {
+			return RoutingInfo{}, err
+		}
+	}
 	info := RoutingInfo{
-		VirtualAddress: parts[1],
-		CommonName:     parts[2],
-		RealAddress:    parts[3],
-		LastRef:        time.Unix(int64(lastRefUnix), 0),
+		VirtualAddress: l.string(parts, "Virtual Address"),
+		CommonName:     l.string(parts, "Common Name"),
+		RealAddress:    l.string(parts, "Real Address"),
+		LastRef:        lastRef,
 	}
 	return info, nil
 }
 
-// ParseStatusFile parses the openvpn-status.log file at `filename` and returns a corresponding slice of ClientInfo and RoutingInfo objects
-func ParseStatusFile(filename string) ([]ClientInfo, []RoutingInfo, error) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil, nil, err
+// checkHeader validates the fields of a HEADER line against the expected ones, if any
+func (o *options) checkHeader(fields []string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	expected, ok := o.expectedHeaders[fields[0]]
+	if !ok {
+		return nil
+	}
+	columns := fields[1:]
+	if len(columns) != len(expected) {
+		return fmt.Errorf("ovpnstats: %s HEADER has %d columns, expected %d", fields[0], len(columns), len(expected))
+	}
+	for i, column := range columns {
+		if column != expected[i] {
+			return fmt.Errorf("ovpnstats: %s HEADER column %d is %q, expected %q", fields[0], i+1, column, expected[i])
+		}
+	}
+	return nil
+}
+
+// ErrUnsupportedVersion is matched, with errors.Is, by the UnsupportedVersionError returned for status files of an
+// unsupported or undetectable format version
+var ErrUnsupportedVersion = errors.New("ovpnstats: unsupported status file version")
+
+// UnsupportedVersionError is returned when the first line of the input isn't a version 2 or 3 record
+type UnsupportedVersionError struct {
+	// Version is the detected version, 1 for "status-version 1" files, or 0 when it couldn't be determined
+	Version int
+	// Line is the first line of the input
+	Line string
+}
+
+func (e *UnsupportedVersionError) Error() string {
+	if e.Version == 0 {
+		return fmt.Sprintf("%v: unknown format of first line %q", ErrUnsupportedVersion, e.Line)
+	}
+	return fmt.Sprintf("%v %d", ErrUnsupportedVersion, e.Version)
+}
+
+// Unwrap returns ErrUnsupportedVersion
+func (e *UnsupportedVersionError) Unwrap() error {
+	return ErrUnsupportedVersion
+}
+
+// versionOneTitle is the first line of "status-version 1" files, which have no record types
+const versionOneTitle = "OpenVPN CLIENT LIST"
+
+// knownRecordTypes are the record types a status file may start with
+var knownRecordTypes = map[string]bool{
+	"TITLE": true, "TIME": true, "HEADER": true, "CLIENT_LIST": true, "ROUTING_TABLE": true, "GLOBAL_STATS": true, "END": true,
+}
+
+// detectVersion returns the status file version of `line`: 3 when its record type is followed by a tab, 2 otherwise.
+// It fails with an UnsupportedVersionError when `line` isn't a record of either version, i.e. its record type is
+// neither a known one nor one of `handlers`
+func detectVersion(line string, handlers map[string]RecordHandler) (int, error) {
+	version := 2
+	if i := strings.Index(line, splitCharacterV3); i >= 0 && !strings.Contains(line[:i], splitCharacter) {
+		version = 3
+	}
+	recordType := strings.SplitN(line, separator(version), 2)[0]
+	if _, ok := handlers[recordType]; ok || knownRecordTypes[recordType] {
+		return version, nil
+	}
+	if strings.TrimSpace(line) == versionOneTitle {
+		return 0, &UnsupportedVersionError{Version: 1, Line: line}
 	}
-	defer file.Close()
+	return 0, &UnsupportedVersionError{Line: line}
+}
+
+// separator returns the field separator of the status file `version`
+func separator(version int) string {
+	if version == 3 {
+		return splitCharacterV3
+	}
+	return splitCharacter
+}
+
+// isBlankOrComment reports whether `line` is empty, whitespace only or a "#" comment, which are skipped
+func isBlankOrComment(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed == "" || strings.HasPrefix(trimmed, "#")
+}
+
+// ErrMissingEnd is returned by WithStrictEnd parses when the input has no END line
+var ErrMissingEnd = errors.New("ovpnstats: missing END line")
 
-	var clients []ClientInfo
-	var routes []RoutingInfo
+// ErrInvalidUTF8 is returned by WithValidateUTF8 parses for a line which isn't valid UTF-8
+var ErrInvalidUTF8 = errors.New("ovpnstats: invalid UTF-8")
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		switch parts := strings.Split(line, splitCharacter); parts[0] {
-		case "HEADER":
-		case "END":
-			break
+// parser holds the state of a single parse
+type parser struct {
+	o      *options
+	status *Status
+	// layouts are the column layouts of each record type, as given by the latest HEADER line of its section.
+	// Sections may come in any order, even interleaved, each HEADER only applies to its own record type
+	layouts    map[string]*layout
+	lineNumber int
+	// ended is set once the END line is found
+	ended bool
+	// deferred collects the CLIENT_LIST and ROUTING_TABLE records instead of parsing them, when not nil
+	deferred *[]*record
+	// handlers are the RecordHandler registered by record type, which replace the built-in parsing of those types
+	handlers map[string]RecordHandler
+	// stream receives the clients and routes kept instead of p.status, when not nil
+	stream *StreamHandler
+}
+
+func newParser(o *options) *parser {
+	return &parser{
+		o:      o,
+		status: &Status{Clock: o.clock},
+		layouts: map[string]*layout{
+			"CLIENT_LIST":   assumedLayout(ClientListColumnsV2, len(ClientListColumnsV24)),
+			"ROUTING_TABLE": assumedLayout(RoutingTableColumns, len(RoutingTableColumns)),
+		},
+	}
+}
+
+// parseLine parses the next line of the status file into p.status
+func (p *parser) parseLine(line string) error {
+	p.lineNumber++
+	o, status := p.o, p.status
+	if isBlankOrComment(line) {
+		o.logger.Printf("ovpnstats: line %d: skipped blank or comment line", p.lineNumber)
+		return nil
+	}
+	if o.validateUTF8 && !utf8.ValidString(line) {
+		return fmt.Errorf("%w: line %d: %q", ErrInvalidUTF8, p.lineNumber, line)
+	}
+	if status.Version == 0 {
+		version, err := detectVersion(line, p.handlers)
+		if err != nil {
+			return err
+		}
+		status.Version = version
+	}
+	sep := separator(status.Version)
+	recordType := line
+	if i := strings.Index(line, sep); i >= 0 {
+		recordType = line[:i]
+	}
+	if _, ok := p.handlers[recordType]; !ok && (recordType == "CLIENT_LIST" || recordType == "ROUTING_TABLE") {
+		// Records are split by record.parse, so that concurrent parses split them in parallel too
+		rec := record{
+			recordType:  recordType,
+			lineNumber:  p.lineNumber,
+			line:        line,
+			sep:         sep,
+			layout:      p.layouts[recordType],
+			timeLayouts: o.timeLayouts,
+			grouping:    o.numberGrouping,
+		}
+		if p.deferred != nil {
+			deferred := rec
+			*p.deferred = append(*p.deferred, &deferred)
+			return nil
+		}
+		// rec doesn't escape, so sequential parses don't allocate it
+		rec.parse()
+		return p.add(&rec)
+	}
+	switch parts := strings.Split(line, sep); parts[0] {
+	case "HEADER":
+		if err := o.checkHeader(parts[1:]); err != nil {
+			return err
+		}
+		if len(parts) > 1 {
+			p.layouts[parts[1]] = newLayout(parts[2:])
+		}
+	case "END":
+		p.ended = true
+	default:
+		if handler, ok := p.handlers[parts[0]]; ok {
+			return handler(parts[1:], status)
+		}
+		switch statusType := parts[0]; statusType {
+		case "TITLE":
+			status.Title = strings.Join(parts[1:], sep)
+		case "TIME":
+			if len(parts) < 3 {
+				return fmt.Errorf("ovpnstats: malformed TIME line %q", line)
+			}
+			updatedAt, err := parseTimestamp(parts[2], parts[1], o.timeLayouts)
+			if err != nil {
+				return err
+			}
+			status.UpdatedAt = o.knownTime(updatedAt)
+		case "GLOBAL_STATS":
+			if len(parts) < 3 {
+				return fmt.Errorf("ovpnstats: malformed GLOBAL_STATS line %q", line)
+			}
+			if status.GlobalStats == nil {
+				status.GlobalStats = make(map[string]string)
+			}
+			status.GlobalStats[parts[1]] = strings.Join(parts[2:], sep)
 		default:
-			switch statusType := parts[0]; statusType {
-			case "CLIENT_LIST":
-				info, err := parseClientListEntry(line)
-				if err != nil {
-					return nil, nil, err
-				}
-				clients = append(clients, info)
-			case "ROUTING_TABLE":
-				info, err := parseRoutingTableEntry(line)
-				if err != nil {
-					return nil, nil, err
-				}
-				routes = append(routes, info)
+			o.logger.Printf("ovpnstats: line %d: skipped unknown record type %q", p.lineNumber, statusType)
+		}
+	}
+	return nil
+}
+
+// record is a CLIENT_LIST or ROUTING_TABLE line and, once parsed, its result
+type record struct {
+	recordType string
+	lineNumber int
+	line       string
+	// sep is the field separator of the line
+	sep    string
+	layout *layout
+	// timeLayouts are the layouts of human readable times
+	timeLayouts []string
+	// grouping is the thousands separator of the numeric CLIENT_LIST columns, if not 0
+	grouping rune
+	client   ClientInfo
+	route    RoutingInfo
+	err      error
+}
+
+// parse splits and parses the record fields. It only depends on the record, so records can be parsed concurrently
+func (r *record) parse() {
+	parts := strings.Split(r.line, r.sep)
+	switch r.recordType {
+	case "CLIENT_LIST":
+		if r.grouping != 0 {
+			r.layout.ungroup(parts, numericClientListColumns, r.grouping)
+		}
+		r.client, r.err = parseClientListEntry(parts, r.layout, r.timeLayouts)
+	case "ROUTING_TABLE":
+		r.route, r.err = parseRoutingTableEntry(parts, r.layout, r.timeLayouts)
+	}
+}
+
+// add appends a parsed record to p.status if it passes the filters, or returns its parsing error.
+// With WithLenient, the parsing error is added to the warnings instead
+func (p *parser) add(r *record) error {
+	if r.err != nil {
+		if !p.o.lenient {
+			return r.err
+		}
+		p.status.Warnings = append(p.status.Warnings, ParseWarning{Line: r.lineNumber, Err: r.err})
+		return nil
+	}
+	switch r.recordType {
+	case "CLIENT_LIST":
+		r.client.ConnectedSince = p.o.knownTime(r.client.ConnectedSince)
+		if p.o.keepClient(r.client) {
+			if p.o.strings != nil {
+				r.client = p.o.strings.internClient(r.client)
+			}
+			if p.stream != nil {
+				return p.stream.client(r.client)
+			}
+			p.status.Clients = append(p.status.Clients, r.client)
+		}
+	case "ROUTING_TABLE":
+		r.route.LastRef = p.o.knownTime(r.route.LastRef)
+		if p.o.keepRoute(r.route) {
+			if p.o.strings != nil {
+				r.route = p.o.strings.internRoute(r.route)
+			}
+			if p.stream != nil {
+				return p.stream.route(r.route)
 			}
+			p.status.Routes = append(p.status.Routes, r.route)
+		}
+	}
+	return nil
+}
+
+// run parses the input `r` into p.status, up to its first END line
+func (p *parser) run(r io.Reader) error {
+	var in *inputReader
+	var lines lineScanner
+	if br, ok := r.(*bufio.Reader); ok && p.o.stopAtEnd {
+		lines = p.o.readerLines(br)
+	} else {
+		in = p.o.input(r)
+		lines = bufio.NewScanner(in)
+	}
+	var err error
+	if p.o.concurrency > 1 {
+		err = p.parseConcurrently(lines, p.o.concurrency)
+	} else {
+		for lines.Scan() {
+			if err = p.parseLine(lines.Text()); err != nil || p.ended {
+				break
+			}
+		}
+	}
+	if err != nil {
+		// A line cut by a limit isn't worth reporting, the limit is
+		if in != nil && in.err() != nil {
+			return in.err()
 		}
+		return err
+	}
+	if err := lines.Err(); err != nil {
+		return err
+	}
+	if p.o.strictEnd && !p.ended {
+		return ErrMissingEnd
+	}
+	if p.o.resolveVirtualFromRoutes {
+		p.status.resolveVirtualAddresses()
+	}
+	return nil
+}
+
+// ParseStatus parses an openvpn-status.log from `r` and returns the corresponding Status.
+// Both the comma separated version 2 and the tab separated version 3 formats are supported, detected from the first line.
+// Other formats, like version 1, fail with an UnsupportedVersionError.
+// Parsing stops at the first END line, ignoring anything after it (see WithStopAtEnd).
+// `r` is read sequentially and never seeked, so it may be a pipe or a remote stream, like an SSH session's output:
+// reads returning any amount of data, down to a byte at a time, give the same result, so a slow reader only delays
+// the parse. WithContext cancels it between reads, but can't interrupt a read blocked in `r`
+func ParseStatus(r io.Reader, opts ...Option) (*Status, error) {
+	p := newParser(newOptions(opts))
+	if err := p.run(r); err != nil {
+		return nil, err
+	}
+	return p.status, nil
+}
+
+// ParseStatusInto parses an openvpn-status.log from `r` like ParseStatus, but into the caller's `clients` and `routes`,
+// which are truncated and then appended to, reusing their capacity. Re-parsing into the same slices, e.g. when polling,
+// avoids allocating them again, which is most of the memory allocated by a parse (see BenchmarkParseStatusInto).
+// The strings of the fields are still allocated. As the elements are overwritten, the caller must not retain pointers to them, nor
+// sub-slices, across calls. On error both slices are left empty
+func ParseStatusInto(r io.Reader, clients *[]ClientInfo, routes *[]RoutingInfo, opts ...Option) error {
+	p := newParser(newOptions(opts))
+	p.status.Clients = (*clients)[:0]
+	p.status.Routes = (*routes)[:0]
+	err := p.run(r)
+	if err != nil {
+		p.status.Clients = p.status.Clients[:0]
+		p.status.Routes = p.status.Routes[:0]
+	}
+	*clients, *routes = p.status.Clients, p.status.Routes
+	return err
+}
+
+// ParseStatusFile parses the openvpn-status.log file at `filename` and returns a corresponding slice of ClientInfo and RoutingInfo objects,
+// even when `filename` is a named pipe (FIFO): opening it waits for a writer, and parsing returns as soon as the first END
+// line is read, without waiting for the writer to close it. Data written after END in the same write may be lost, and
+// later writes fail once the pipe is closed. Don't combine a FIFO with WithAtomicRead, which reads until the writer closes it
+func ParseStatusFile(filename string, opts ...Option) ([]ClientInfo, []RoutingInfo, error) {
+	status, err := parseStatusFile(filename, opts)
+	if err != nil {
+		return nil, nil, err
 	}
-	return clients, routes, nil
+	return status.Clients, status.Routes, nil
 }
@@ -0,0 +1,69 @@
+package ovpnstats_test
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+// benchmarkClients is the number of clients of the benchmarked status files, the size of a large gateway
+const benchmarkClients = 50000
+
+// statusFile returns a version 2 status file with `clients` clients and a route to each of them
+func statusFile(clients int) []byte {
+	var b bytes.Buffer
+	b.WriteString("TITLE,OpenVPN 2.6.8 x86_64-pc-linux-gnu\n")
+	b.WriteString("TIME,2021-03-01 10:00:00,1614592800\n")
+	b.WriteString("HEADER,CLIENT_LIST,Common Name,Real Address,Virtual Address,Virtual IPv6 Address,Bytes Received,Bytes Sent,Connected Since,Connected Since (time_t),Username,Client ID,Peer ID,Data Channel Cipher\n")
+	for i := 0; i < clients; i++ {
+		fmt.Fprintf(&b, "CLIENT_LIST,client%d,203.0.%d.%d:%d,10.8.%d.%d,,%d,%d,2021-03-01 09:00:00,1614589200,user%d,%d,%d,AES-256-GCM\n",
+			i, i/256%256, i%256, 1024+i%60000, i/256%256, i%256, 1000*i, 2000*i, i, i, i)
+	}
+	b.WriteString("HEADER,ROUTING_TABLE,Virtual Address,Common Name,Real Address,Last Ref,Last Ref (time_t)\n")
+	for i := 0; i < clients; i++ {
+		fmt.Fprintf(&b, "ROUTING_TABLE,10.8.%d.%d,client%d,203.0.%d.%d:%d,2021-03-01 09:59:00,1614592740\n",
+			i/256%256, i%256, i, i/256%256, i%256, 1024+i%60000)
+	}
+	b.WriteString("GLOBAL_STATS,Max bcast/mcast queue length,0\n")
+	b.WriteString("END\n")
+	return b.Bytes()
+}
+
+func benchmarkParseStatus(b *testing.B, opts ...ovpnstats.Option) {
+	input := statusFile(benchmarkClients)
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ovpnstats.ParseStatus(bytes.NewReader(input), opts...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseStatus(b *testing.B) {
+	benchmarkParseStatus(b)
+}
+
+// BenchmarkParseStatusConcurrent uses a worker per CPU: compare it to BenchmarkParseStatus with -cpu 1,4
+func BenchmarkParseStatusConcurrent(b *testing.B) {
+	benchmarkParseStatus(b, ovpnstats.WithConcurrency(runtime.GOMAXPROCS(0)))
+}
+
+// BenchmarkParseStatusInto re-parses into the same slices, like a polling loop: compare its allocations to BenchmarkParseStatus
+func BenchmarkParseStatusInto(b *testing.B) {
+	input := statusFile(benchmarkClients)
+	var clients []ovpnstats.ClientInfo
+	var routes []ovpnstats.RoutingInfo
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := ovpnstats.ParseStatusInto(bytes.NewReader(input), &clients, &routes); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,486 @@
+package ovpnstats_test
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/emibcn/ovpnstats"
+	"github.com/emibcn/ovpnstats/ovpnstatstest"
+)
+
+// statusV2 is a version 2 status file with every section
+const statusV2 = "TITLE,OpenVPN 2.6.8 x86_64-pc-linux-gnu\n" +
+	"TIME,2021-03-01 10:00:00,1614592800\n" +
+	"HEADER,CLIENT_LIST,Common Name,Real Address,Virtual Address,Virtual IPv6 Address,Bytes Received,Bytes Sent,Connected Since,Connected Since (time_t),Username,Client ID,Peer ID,Data Channel Cipher\n" +
+	"CLIENT_LIST,alice,203.0.113.5:1194,10.8.0.2,fd00::2,100,200,2021-03-01 09:00:00,1614589200,alice,0,0,AES-256-GCM\n" +
+	"CLIENT_LIST,bob,[2001:db8::1]:50123,10.8.0.3,,300,400,2021-03-01 09:30:00,1614591000,UNDEF,1,1,CHACHA20-POLY1305\n" +
+	"HEADER,ROUTING_TABLE,Virtual Address,Common Name,Real Address,Last Ref,Last Ref (time_t)\n" +
+	"ROUTING_TABLE,10.8.0.2,alice,203.0.113.5:1194,2021-03-01 09:59:00,1614592740\n" +
+	"ROUTING_TABLE,10.8.0.3,bob,[2001:db8::1]:50123,2021-03-01 09:58:00,1614592680\n" +
+	"GLOBAL_STATS,Max bcast/mcast queue length,0\n" +
+	"END\n"
+
+// mustParse parses `input` with `opts`, failing `t` on error
+func mustParse(t *testing.T, input string, opts ...ovpnstats.Option) *ovpnstats.Status {
+	t.Helper()
+	status, err := ovpnstats.ParseStatus(strings.NewReader(input), opts...)
+	if err != nil {
+		t.Fatalf("ParseStatus: %v", err)
+	}
+	return status
+}
+
+func TestParseStatusIPv6Routes(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		virtual   string
+		virtualIP net.IP
+		real      string
+		realIP    net.IP
+	}{
+		{
+			name:      "bracketed real address",
+			line:      "ROUTING_TABLE,2001:db8::2,alice,[2001:db8::1]:1194,2021-03-01 10:00:00,1614592800",
+			virtual:   "2001:db8::2",
+			virtualIP: net.ParseIP("2001:db8::2"),
+			real:      "[2001:db8::1]:1194",
+			realIP:    net.ParseIP("2001:db8::1"),
+		},
+		{
+			name:      "bare real address",
+			line:      "ROUTING_TABLE,2001:db8::2,alice,2001:db8::1,2021-03-01 10:00:00,1614592800",
+			virtual:   "2001:db8::2",
+			virtualIP: net.ParseIP("2001:db8::2"),
+			real:      "2001:db8::1",
+			realIP:    net.ParseIP("2001:db8::1"),
+		},
+		{
+			name:      "protocol prefixed real address",
+			line:      "ROUTING_TABLE,2001:db8::2,alice,udp6:[2001:db8::1]:1194,2021-03-01 10:00:00,1614592800",
+			virtual:   "2001:db8::2",
+			virtualIP: net.ParseIP("2001:db8::2"),
+			real:      "udp6:[2001:db8::1]:1194",
+			realIP:    net.ParseIP("2001:db8::1"),
+		},
+		{
+			name:      "IPv6 subnet route",
+			line:      "ROUTING_TABLE,2001:db8:1::/64,alice,[2001:db8::1]:1194,2021-03-01 10:00:00,1614592800",
+			virtual:   "2001:db8:1::/64",
+			virtualIP: nil,
+			real:      "[2001:db8::1]:1194",
+			realIP:    net.ParseIP("2001:db8::1"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status := mustParse(t, "HEADER,ROUTING_TABLE,Virtual Address,Common Name,Real Address,Last Ref,Last Ref (time_t)\n"+test.line+"\nEND\n")
+			if len(status.Routes) != 1 {
+				t.Fatalf("got %d routes, want 1", len(status.Routes))
+			}
+			route := status.Routes[0]
+			if route.VirtualAddress != test.virtual || route.RealAddress != test.real {
+				t.Errorf("got Virtual Address %q and Real Address %q, want %q and %q", route.VirtualAddress, route.RealAddress, test.virtual, test.real)
+			}
+			if !route.VirtualIP().Equal(test.virtualIP) {
+				t.Errorf("VirtualIP() = %v, want %v", route.VirtualIP(), test.virtualIP)
+			}
+			if !route.RealIP().Equal(test.realIP) {
+				t.Errorf("RealIP() = %v, want %v", route.RealIP(), test.realIP)
+			}
+		})
+	}
+}
+
+func TestParseStatusSkipsBlankAndCommentLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{
+			name:  "trailing blank lines",
+			input: "CLIENT_LIST,alice,203.0.113.5:1194,10.8.0.2,,1,2,2021-03-01 10:00:00,1614592800,UNDEF,0,0,AES-256-GCM\nEND\n\n\n",
+		},
+		{
+			name:  "blank and whitespace lines between records",
+			input: "\nCLIENT_LIST,alice,203.0.113.5:1194,10.8.0.2,,1,2,2021-03-01 10:00:00,1614592800,UNDEF,0,0,AES-256-GCM\n \t\nEND\n",
+		},
+		{
+			name:  "comment with commas",
+			input: "# CLIENT_LIST,mallory,198.51.100.1:1194\nCLIENT_LIST,alice,203.0.113.5:1194,10.8.0.2,,1,2,2021-03-01 10:00:00,1614592800,UNDEF,0,0,AES-256-GCM\nEND\n",
+		},
+		{
+			name:  "indented comment",
+			input: "CLIENT_LIST,alice,203.0.113.5:1194,10.8.0.2,,1,2,2021-03-01 10:00:00,1614592800,UNDEF,0,0,AES-256-GCM\n  #,x,y\nEND\n",
+		},
+		{
+			name:  "CRLF blank line",
+			input: "CLIENT_LIST,alice,203.0.113.5:1194,10.8.0.2,,1,2,2021-03-01 10:00:00,1614592800,UNDEF,0,0,AES-256-GCM\r\n\r\nEND\r\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status := mustParse(t, test.input, ovpnstats.WithStrictEnd())
+			if len(status.Clients) != 1 || status.Clients[0].Name != "alice" {
+				t.Fatalf("got clients %+v, want only alice", status.Clients)
+			}
+		})
+	}
+}
+
+func TestParseStatusShortRecords(t *testing.T) {
+	const header = "HEADER,CLIENT_LIST,Common Name,Real Address,Virtual Address,Virtual IPv6 Address,Bytes Received,Bytes Sent,Connected Since,Connected Since (time_t),Username,Client ID,Peer ID,Data Channel Cipher,Pool\n"
+	tests := []struct {
+		name      string
+		input     string
+		wantErr   string
+		wantExtra map[string]string
+	}{
+		{
+			name:    "truncated row",
+			input:   header + "CLIENT_LIST,alice,203.0.11\nEND\n",
+			wantErr: "ovpnstats: CLIENT_LIST has 2 fields, HEADER has 13",
+		},
+		{
+			name:    "row missing an extra column",
+			input:   header + "CLIENT_LIST,alice,203.0.113.5:1194,10.8.0.2,,1,2,2021-03-01 10:00:00,1614592800,UNDEF,0,0,AES-256-GCM\nEND\n",
+			wantErr: "ovpnstats: CLIENT_LIST has 12 fields, HEADER has 13",
+		},
+		{
+			name:      "extra column",
+			input:     header + "CLIENT_LIST,alice,203.0.113.5:1194,10.8.0.2,,1,2,2021-03-01 10:00:00,1614592800,UNDEF,0,0,AES-256-GCM,main\nEND\n",
+			wantExtra: map[string]string{"Pool": "main"},
+		},
+		{
+			name:    "truncated row without HEADER",
+			input:   "CLIENT_LIST,alice,203.0.11\nEND\n",
+			wantErr: "ovpnstats: CLIENT_LIST has 2 fields, expected at least 11",
+		},
+		{
+			name:  "OpenVPN 2.4 row without HEADER",
+			input: "CLIENT_LIST,alice,203.0.113.5:1194,10.8.0.2,,1,2,2021-03-01 10:00:00,1614592800,UNDEF,0,0\nEND\n",
+		},
+		{
+			name:    "truncated route",
+			input:   "ROUTING_TABLE,10.8.0.2,alice\nEND\n",
+			wantErr: "ovpnstats: ROUTING_TABLE has 2 fields, expected at least 5",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ovpnstats.ParseStatus(strings.NewReader(test.input))
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ParseStatus: %v", err)
+				}
+			} else if err == nil || err.Error() != test.wantErr {
+				t.Fatalf("ParseStatus error = %v, want %q", err, test.wantErr)
+			}
+
+			status := mustParse(t, test.input, ovpnstats.WithLenient())
+			if test.wantErr == "" {
+				if len(status.Clients) != 1 || len(status.Warnings) != 0 {
+					t.Fatalf("lenient parse gave clients %+v and warnings %v, want a client alone", status.Clients, status.Warnings)
+				}
+				if extra := status.Clients[0].Extra; len(extra) != len(test.wantExtra) || extra["Pool"] != test.wantExtra["Pool"] {
+					t.Errorf("Extra = %v, want %v", extra, test.wantExtra)
+				}
+				return
+			}
+			if len(status.Clients)+len(status.Routes) != 0 {
+				t.Errorf("lenient parse kept clients %+v and routes %+v", status.Clients, status.Routes)
+			}
+			if len(status.Warnings) != 1 || status.Warnings[0].Err.Error() != test.wantErr {
+				t.Errorf("lenient parse warnings = %v, want one with %q", status.Warnings, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseStatusReorderedSections(t *testing.T) {
+	const (
+		clientHeader = "HEADER,CLIENT_LIST,Common Name,Real Address,Virtual Address,Bytes Received,Bytes Sent,Connected Since (time_t)\n"
+		client       = "CLIENT_LIST,alice,203.0.113.5:1194,10.8.0.2,100,200,1614592800\n"
+		routeHeader  = "HEADER,ROUTING_TABLE,Common Name,Virtual Address,Real Address,Last Ref (time_t)\n"
+		route        = "ROUTING_TABLE,alice,10.8.0.2,203.0.113.5:1194,1614592900\n"
+	)
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "client list first", input: clientHeader + client + routeHeader + route + "END\n"},
+		{name: "routing table first", input: routeHeader + route + clientHeader + client + "END\n"},
+		{name: "headers first", input: clientHeader + routeHeader + route + client + "END\n"},
+		{name: "interleaved", input: routeHeader + clientHeader + client + route + client + route + "END\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status := mustParse(t, test.input)
+			if len(status.Clients) == 0 || len(status.Routes) == 0 {
+				t.Fatalf("got %d clients and %d routes, want some of both", len(status.Clients), len(status.Routes))
+			}
+			for _, c := range status.Clients {
+				if c.Name != "alice" || c.VirtualAddress != "10.8.0.2" || c.BytesReceived != 100 || c.BytesSent != 200 || c.ConnectedSinceUnix() != 1614592800 {
+					t.Errorf("got client %+v", c)
+				}
+			}
+			for _, r := range status.Routes {
+				if r.CommonName != "alice" || r.VirtualAddress != "10.8.0.2" || r.RealAddress != "203.0.113.5:1194" || r.LastRefUnix() != 1614592900 {
+					t.Errorf("got route %+v", r)
+				}
+			}
+		})
+	}
+}
+
+func TestParseStatusMissingEnd(t *testing.T) {
+	const input = "HEADER,CLIENT_LIST,Common Name,Real Address,Virtual Address,Virtual IPv6 Address,Bytes Received,Bytes Sent,Connected Since,Connected Since (time_t),Username,Client ID,Peer ID,Data Channel Cipher\n" +
+		"CLIENT_LIST,alice,203.0.113.5:1194,10.8.0.2,,1,2,2021-03-01 10:00:00,1614592800,UNDEF,0,0,AES-256-GCM\n" +
+		"CLIENT_LIST,bob,198.51.100.7:1194,10.8.0.3,,3,4,2021-03-01 10:00:00,1614592800,UNDEF,1,1,AES-256-GCM"
+	tests := []struct {
+		name    string
+		opts    []ovpnstats.Option
+		wantErr error
+	}{
+		{name: "default"},
+		{name: "strict END", opts: []ovpnstats.Option{ovpnstats.WithStrictEnd()}, wantErr: ovpnstats.ErrMissingEnd},
+		{name: "stop at END", opts: []ovpnstats.Option{ovpnstats.WithStopAtEnd()}},
+		{name: "stop at strict END", opts: []ovpnstats.Option{ovpnstats.WithStopAtEnd(), ovpnstats.WithStrictEnd()}, wantErr: ovpnstats.ErrMissingEnd},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// A *bufio.Reader is read line by line with WithStopAtEnd
+			status, err := ovpnstats.ParseStatus(bufio.NewReader(strings.NewReader(input)), test.opts...)
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("ParseStatus error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseStatus: %v", err)
+			}
+			if len(status.Clients) != 2 {
+				t.Fatalf("got %d clients, want 2", len(status.Clients))
+			}
+			if last := status.Clients[1]; last.Name != "bob" || last.DataChannelCipher != "AES-256-GCM" || last.BytesSent != 4 {
+				t.Errorf("got last client %+v, want bob with all its fields", last)
+			}
+		})
+	}
+}
+
+func TestParseStatusInvalidUTF8(t *testing.T) {
+	tests := []struct {
+		name       string
+		commonName string
+		opts       []ovpnstats.Option
+		wantErr    error
+	}{
+		{name: "raw bytes kept", commonName: "caf\xe9"},
+		{name: "validated", commonName: "caf\xe9", opts: []ovpnstats.Option{ovpnstats.WithValidateUTF8()}, wantErr: ovpnstats.ErrInvalidUTF8},
+		{name: "validated leniently", commonName: "caf\xe9", opts: []ovpnstats.Option{ovpnstats.WithValidateUTF8(), ovpnstats.WithLenient()}, wantErr: ovpnstats.ErrInvalidUTF8},
+		{name: "valid non-ASCII", commonName: "café", opts: []ovpnstats.Option{ovpnstats.WithValidateUTF8()}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input := "CLIENT_LIST," + test.commonName + ",203.0.113.5:1194,10.8.0.2,,1,2,2021-03-01 10:00:00,1614592800,UNDEF,0,0,AES-256-GCM\nEND\n"
+			status, err := ovpnstats.ParseStatus(strings.NewReader(input), test.opts...)
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("ParseStatus error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseStatus: %v", err)
+			}
+			if len(status.Clients) != 1 || status.Clients[0].Name != test.commonName {
+				t.Errorf("got clients %+v, want one named %q", status.Clients, test.commonName)
+			}
+		})
+	}
+}
+
+func TestParseStatusOneByteReader(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "version 2", input: statusV2},
+		{name: "version 3", input: strings.ReplaceAll(statusV2, ",", "\t")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			want := mustParse(t, test.input)
+			// Readers returning a byte at a time, like a slow pipe, with the last one coming along with io.EOF
+			for _, r := range []io.Reader{
+				iotest.OneByteReader(strings.NewReader(test.input)),
+				iotest.DataErrReader(iotest.OneByteReader(strings.NewReader(test.input))),
+			} {
+				got, err := ovpnstats.ParseStatus(r)
+				if err != nil {
+					t.Fatalf("ParseStatus: %v", err)
+				}
+				ovpnstatstest.AssertStatusEqual(t, want, got)
+				if got.Version != want.Version || len(got.Clients) != 2 {
+					t.Errorf("got version %d with %d clients, want version %d with 2", got.Version, len(got.Clients), want.Version)
+				}
+			}
+		})
+	}
+}
+
+func TestParseStatusFileOpenVPN24(t *testing.T) {
+	clients, routes, err := ovpnstats.ParseStatusFile("testdata/status-2.4.log", ovpnstats.WithStrictEnd())
+	if err != nil {
+		t.Fatalf("ParseStatusFile: %v", err)
+	}
+	want := []ovpnstats.ClientInfo{
+		{
+			Name:           "alice",
+			RealAddress:    "203.0.113.5:50123",
+			VirtualAddress: "10.8.0.6",
+			BytesReceived:  1833149,
+			BytesSent:      4376416,
+			ConnectedSince: time.Unix(1646122361, 0),
+			Username:       "UNDEF",
+			ClientID:       4,
+			PeerID:         0,
+		},
+		{
+			Name:           "bob",
+			RealAddress:    "198.51.100.7:41781",
+			VirtualAddress: "10.8.0.10",
+			BytesReceived:  269315,
+			BytesSent:      1104918,
+			ConnectedSince: time.Unix(1646128023, 0),
+			Username:       "bob",
+			ClientID:       7,
+			PeerID:         1,
+		},
+	}
+	if len(clients) != len(want) {
+		t.Fatalf("got %d clients, want %d", len(clients), len(want))
+	}
+	for i, client := range clients {
+		if !client.Equal(want[i]) {
+			t.Errorf("client %d = %+v, want %+v", i, client, want[i])
+		}
+		if client.DataChannelCipher != "" || client.Extra != nil {
+			t.Errorf("client %d has Data Channel Cipher %q and Extra %v, want none", i, client.DataChannelCipher, client.Extra)
+		}
+	}
+	if len(routes) != 2 || routes[1].VirtualAddress != "10.8.0.10" || routes[1].LastRefUnix() != 1646128791 {
+		t.Errorf("got routes %+v", routes)
+	}
+}
+
+// countingReader counts the bytes read from it
+type countingReader struct {
+	r    io.Reader
+	read int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += n
+	return n, err
+}
+
+func TestParseStatusStopAtEnd(t *testing.T) {
+	const trailer = ">INFO:OpenVPN Management Interface\n"
+	tests := []struct {
+		name        string
+		input       string
+		opts        []ovpnstats.Option
+		wantErr     error
+		wantTrailer bool
+		// maxRead bounds the bytes read from the underlying reader, unless 0
+		maxRead int
+	}{
+		{name: "rest kept", input: statusV2 + trailer, wantTrailer: true},
+		{name: "rest kept within WithMaxBytes", input: statusV2 + trailer, opts: []ovpnstats.Option{ovpnstats.WithMaxBytes(int64(len(statusV2)))}, wantTrailer: true},
+		{name: "status beyond WithMaxBytes", input: statusV2, opts: []ovpnstats.Option{ovpnstats.WithMaxBytes(100)}, wantErr: ovpnstats.ErrInputTooLarge},
+		{
+			name:    "long line beyond WithMaxBytes",
+			input:   "TITLE," + strings.Repeat("x", 1<<20),
+			opts:    []ovpnstats.Option{ovpnstats.WithMaxBytes(1024)},
+			wantErr: ovpnstats.ErrInputTooLarge,
+			maxRead: 1024 + 4096,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			counter := &countingReader{r: strings.NewReader(test.input)}
+			r := bufio.NewReader(counter)
+			status, err := ovpnstats.ParseStatus(r, append(test.opts, ovpnstats.WithStopAtEnd())...)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("ParseStatus error = %v, want %v", err, test.wantErr)
+			}
+			if test.maxRead > 0 && counter.read > test.maxRead {
+				t.Errorf("read %d bytes, want at most %d", counter.read, test.maxRead)
+			}
+			if !test.wantTrailer {
+				return
+			}
+			if len(status.Clients) != 2 {
+				t.Errorf("got %d clients, want 2", len(status.Clients))
+			}
+			if rest, _ := io.ReadAll(r); string(rest) != trailer {
+				t.Errorf("read %q after the status, want %q", rest, trailer)
+			}
+		})
+	}
+}
+
+func TestParseStatusUnsupportedVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		wantVersion int
+		wantLine    string
+		wantErr     string
+	}{
+		{
+			name:        "version 1",
+			input:       "OpenVPN CLIENT LIST\nUpdated,Mon Mar  1 10:00:00 2021\nCommon Name,Real Address,Bytes Received,Bytes Sent,Connected Since\n",
+			wantVersion: 1,
+			wantLine:    "OpenVPN CLIENT LIST",
+			wantErr:     "ovpnstats: unsupported status file version 1",
+		},
+		{
+			name:     "unknown format",
+			input:    "Common Name,Real Address\nalice,203.0.113.5:1194\n",
+			wantLine: "Common Name,Real Address",
+			wantErr:  `ovpnstats: unsupported status file version: unknown format of first line "Common Name,Real Address"`,
+		},
+		{
+			name:     "unknown record type after blank and comment lines",
+			input:    "\n# written by a cron job\nCLIENTS\talice\n" + statusV2,
+			wantLine: "CLIENTS\talice",
+			wantErr:  `ovpnstats: unsupported status file version: unknown format of first line "CLIENTS\talice"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ovpnstats.ParseStatus(strings.NewReader(test.input))
+			var versionErr *ovpnstats.UnsupportedVersionError
+			if !errors.As(err, &versionErr) {
+				t.Fatalf("ParseStatus error = %v, want an UnsupportedVersionError", err)
+			}
+			if versionErr.Version != test.wantVersion || versionErr.Line != test.wantLine {
+				t.Errorf("got Version %d and Line %q, want %d and %q", versionErr.Version, versionErr.Line, test.wantVersion, test.wantLine)
+			}
+			if !errors.Is(err, ovpnstats.ErrUnsupportedVersion) || err.Error() != test.wantErr {
+				t.Errorf("ParseStatus error = %v, want %q matching ErrUnsupportedVersion", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package ovpnstats
+
+// PeakConcurrencyByPrefix returns, for every network seen in `snapshots`, the highest number of
+// clients connected at the same time from it. Networks are the clients' Real Address masked to
+// `v4Len` bits for IPv4 and `v6Len` bits for IPv6 (e.g. 24 and 64), capped at the length of each
+// family, and keyed in CIDR notation (e.g. "203.0.113.0/24" or "2001:db8::/64").
+// Clients whose Real Address can't be parsed are ignored
+func PeakConcurrencyByPrefix(snapshots []*Status, v4Len, v6Len int) map[string]int {
+	peak := make(map[string]int)
+	for _, snapshot := range snapshots {
+		if snapshot == nil {
+			continue
+		}
+		count := make(map[string]int)
+		for _, client := range snapshot.Clients {
+			ip, ok := client.Address(AddressReal)
+			if !ok {
+				continue
+			}
+			count[maskIP(ip, v4Len, v6Len)]++
+		}
+		for network, n := range count {
+			if n > peak[network] {
+				peak[network] = n
+			}
+		}
+	}
+	return peak
+}
+
+// UnknownPrefix is the ClientsByRealPrefix key of the clients without a parseable Real Address, e.g. "UNDEF" while connecting
+const UnknownPrefix = "unknown"
+
+// ClientsByRealPrefix groups the clients of `s` by their Real Address masked to `v4Len` bits for IPv4 and `v6Len` bits
+// for IPv6, capped at the length of each family, and keyed in CIDR notation like PeakConcurrencyByPrefix does, so IPv4
+// and IPv6 clients never share a group. Clients whose Real Address can't be parsed are grouped under UnknownPrefix
+func (s *Status) ClientsByRealPrefix(v4Len, v6Len int) map[string][]ClientInfo {
+	groups := make(map[string][]ClientInfo)
+	for _, client := range s.Clients {
+		key := UnknownPrefix
+		if ip, ok := client.Address(AddressReal); ok {
+			key = maskIP(ip, v4Len, v6Len)
+		}
+		groups[key] = append(groups[key], client)
+	}
+	return groups
+}
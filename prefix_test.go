@@ -0,0 +1,46 @@
+package ovpnstats_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+func TestPrefixPerFamily(t *testing.T) {
+	first := &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{
+		{Name: "alice", RealAddress: "203.0.113.5:1194"},
+		{Name: "bob", RealAddress: "203.0.113.200:1194"},
+		{Name: "carol", RealAddress: "[2001:db8:0:1::1]:1194"},
+		{Name: "dave", RealAddress: "[2001:db8:0:1:ffff::2]:1194"},
+		{Name: "erin", RealAddress: "[2001:db8:0:2::1]:1194"},
+		{Name: "frank", RealAddress: "UNDEF"},
+	}}
+	second := &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{
+		{Name: "alice", RealAddress: "203.0.113.5:1194"},
+		{Name: "erin", RealAddress: "[2001:db8:0:2::1]:1194"},
+		{Name: "grace", RealAddress: "[2001:db8:0:2::3]:1194"},
+		{Name: "heidi", RealAddress: "[2001:db8:0:2::4]:1194"},
+	}}
+
+	wantPeak := map[string]int{"203.0.113.0/24": 2, "2001:db8:0:1::/64": 2, "2001:db8:0:2::/64": 3}
+	if got := ovpnstats.PeakConcurrencyByPrefix([]*ovpnstats.Status{first, nil, second}, 24, 64); !reflect.DeepEqual(got, wantPeak) {
+		t.Errorf("PeakConcurrencyByPrefix(24, 64) = %v, want %v", got, wantPeak)
+	}
+
+	wantGroups := map[string][]string{
+		"203.0.113.5/32":        {"alice"},
+		"203.0.113.200/32":      {"bob"},
+		"2001:db8::/32":         {"carol", "dave", "erin"},
+		ovpnstats.UnknownPrefix: {"frank"},
+	}
+	got := make(map[string][]string)
+	for network, clients := range first.ClientsByRealPrefix(64, 32) {
+		for _, client := range clients {
+			got[network] = append(got[network], client.Name)
+		}
+	}
+	if !reflect.DeepEqual(got, wantGroups) {
+		t.Errorf("ClientsByRealPrefix(64, 32) = %v, want %v", got, wantGroups)
+	}
+}
@@ -0,0 +1,83 @@
+package ovpnstats
+
+import (
+	"net"
+	"time"
+)
+
+// Query is a selection of the clients of a Status, narrowed by chaining its methods, e.g.
+//
+//	s.Query().ConnectedAfter(t).WithCipher("AES-256-GCM").FromCIDR(network).Clients()
+//
+// Each method returns a new Query, leaving the one it's called on and the Status untouched, so a Query can be
+// the base of several ones. Conditions are evaluated by Clients and Routes, against the Status as it is then
+type Query struct {
+	s    *Status
+	keep func(ClientInfo) bool
+}
+
+// Query returns a Query selecting all the clients of `s`
+func (s *Status) Query() Query {
+	return Query{s: s}
+}
+
+// Where narrows the selection to the clients for which `keep` returns true
+func (q Query) Where(keep func(ClientInfo) bool) Query {
+	if q.keep == nil {
+		return Query{s: q.s, keep: keep}
+	}
+	previous := q.keep
+	return Query{s: q.s, keep: func(client ClientInfo) bool { return previous(client) && keep(client) }}
+}
+
+// ConnectedAfter narrows the selection to the clients connected after `t`
+func (q Query) ConnectedAfter(t time.Time) Query {
+	return q.Where(func(client ClientInfo) bool { return client.ConnectedSince.After(t) })
+}
+
+// WithCommonName narrows the selection to the clients with Common Name `name`
+func (q Query) WithCommonName(name string) Query {
+	return q.Where(func(client ClientInfo) bool { return client.Name == name })
+}
+
+// WithCipher narrows the selection to the clients using the Data Channel Cipher `cipher`, compared like BytesByCipher
+// does: ignoring case, with "none" matching clients without cipher
+func (q Query) WithCipher(cipher string) Query {
+	cipher = normalizeCipher(cipher)
+	return q.Where(func(client ClientInfo) bool { return normalizeCipher(client.DataChannelCipher) == cipher })
+}
+
+// FromCIDR narrows the selection to the clients whose Real Address is in `network`.
+// Clients without a valid Real Address, like those still connecting, are never in it
+func (q Query) FromCIDR(network *net.IPNet) Query {
+	return q.Where(func(client ClientInfo) bool {
+		ip := client.RealIP()
+		return ip != nil && network.Contains(ip)
+	})
+}
+
+// Clients returns the selected clients, in client list order
+func (q Query) Clients() []ClientInfo {
+	var clients []ClientInfo
+	for _, client := range q.s.Clients {
+		if q.keep == nil || q.keep(client) {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
+// Routes returns the routes of the selected clients, correlated like RoutesFor does, in routing table order
+func (q Query) Routes() []RoutingInfo {
+	selected := make(map[endpoint]bool)
+	for _, client := range q.Clients() {
+		selected[newEndpoint(client.Name, client.RealAddress)] = true
+	}
+	var routes []RoutingInfo
+	for _, route := range q.s.Routes {
+		if selected[newEndpoint(route.CommonName, route.RealAddress)] {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
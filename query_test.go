@@ -0,0 +1,76 @@
+package ovpnstats_test
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+func TestQuery(t *testing.T) {
+	s := mustParse(t, strings.NewReplacer(
+		"HEADER,ROUTING_TABLE", "CLIENT_LIST,carol,,,,0,0,2021-03-01 09:45:00,1614591900,UNDEF,2,2,\nHEADER,ROUTING_TABLE",
+	).Replace(statusV2))
+	_, ipv4, err := net.ParseCIDR("203.0.113.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, everything, err := net.ParseCIDR("::/0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		query      ovpnstats.Query
+		want       []string
+		wantRoutes []string
+	}{
+		{name: "everything", query: s.Query(), want: []string{"alice", "bob", "carol"}, wantRoutes: []string{"10.8.0.2", "10.8.0.3"}},
+		{name: "connected after", query: s.Query().ConnectedAfter(time.Unix(1614589200, 0)), want: []string{"bob", "carol"}, wantRoutes: []string{"10.8.0.3"}},
+		{name: "common name", query: s.Query().WithCommonName("bob"), want: []string{"bob"}, wantRoutes: []string{"10.8.0.3"}},
+		{name: "cipher ignores case", query: s.Query().WithCipher("aes-256-gcm"), want: []string{"alice"}, wantRoutes: []string{"10.8.0.2"}},
+		{name: "cipher none", query: s.Query().WithCipher("none"), want: []string{"carol"}},
+		{name: "CIDR", query: s.Query().FromCIDR(ipv4), want: []string{"alice"}, wantRoutes: []string{"10.8.0.2"}},
+		{name: "CIDR skips clients without Real Address", query: s.Query().FromCIDR(everything), want: []string{"bob"}, wantRoutes: []string{"10.8.0.3"}},
+		{name: "chained", query: s.Query().ConnectedAfter(time.Unix(1614589200, 0)).WithCipher("CHACHA20-POLY1305"), want: []string{"bob"}, wantRoutes: []string{"10.8.0.3"}},
+		{name: "chained without match", query: s.Query().WithCommonName("alice").ConnectedAfter(time.Unix(1614589200, 0))},
+		{
+			name:  "where",
+			query: s.Query().Where(func(client ovpnstats.ClientInfo) bool { return client.BytesSent > 300 }),
+			want:  []string{"bob"}, wantRoutes: []string{"10.8.0.3"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := names(test.query.Clients()); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Clients() = %v, want %v", got, test.want)
+			}
+			if got := virtualAddresses(test.query.Routes()); !reflect.DeepEqual(got, test.wantRoutes) {
+				t.Errorf("Routes() = %v, want %v", got, test.wantRoutes)
+			}
+		})
+	}
+}
+
+func TestQueryBranches(t *testing.T) {
+	s := mustParse(t, statusV2)
+	base := s.Query().ConnectedAfter(time.Unix(1614585600, 0))
+	alice := base.WithCommonName("alice")
+	bob := base.WithCommonName("bob")
+	if got := names(base.Clients()); !reflect.DeepEqual(got, []string{"alice", "bob"}) {
+		t.Errorf("base Clients() = %v after narrowing it, want alice and bob", got)
+	}
+	if got := names(alice.Clients()); !reflect.DeepEqual(got, []string{"alice"}) {
+		t.Errorf("alice Clients() = %v, want alice", got)
+	}
+	if got := names(bob.Clients()); !reflect.DeepEqual(got, []string{"bob"}) {
+		t.Errorf("bob Clients() = %v, want bob", got)
+	}
+	if len(s.Clients) != 2 {
+		t.Errorf("Query changed the Status clients to %v", names(s.Clients))
+	}
+}
@@ -0,0 +1,120 @@
+package ovpnstats
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// RedactMode selects how Redact replaces values
+type RedactMode int
+
+const (
+	// RedactMask keeps the network part of IPs and masks the rest: "203.0.113.x" for IPv4,
+	// the first 48 bits followed by "::x" for IPv6 (e.g. "2001:db8:1::x"). Other values become "x"
+	RedactMask RedactMode = iota
+	// RedactHash replaces values with a keyed hash, the same for the same value and key,
+	// so a client can still be followed across snapshots without revealing its address
+	RedactHash
+)
+
+// ErrRedactKeyRequired is returned by Redact for RedactHash without a Key
+var ErrRedactKeyRequired = errors.New("ovpnstats: RedactHash requires a Key")
+
+// RedactOptions configures Redact. Real Addresses are always redacted
+type RedactOptions struct {
+	Mode RedactMode
+	// Key is the secret mixed into RedactHash hashes, so they can't be reversed by hashing every possible address.
+	// Keep it stable to correlate the hashes of several snapshots. It's required by RedactHash: an empty key is known
+	// to anyone, who could then reverse the hashes of the whole IPv4 space
+	Key []byte
+	// VirtualAddresses also redacts the Virtual Addresses of clients and routes
+	VirtualAddresses bool
+	// Usernames also redacts the Usernames of clients
+	Usernames bool
+}
+
+// Redact returns a copy of `s` with its addresses, and optionally usernames, redacted for privacy-safe exports.
+// Ports are dropped from redacted addresses. Empty and "UNDEF" values are kept, as they reveal nothing.
+// The values of ClientInfo.Extra and Metadata are always redacted, as unknown columns may hold addresses too,
+// and Warnings are dropped, as their errors may quote the skipped lines.
+// `s` isn't modified, and a nil `s` gives nil. It fails with ErrRedactKeyRequired when opts.Mode is RedactHash
+// and opts.Key is empty
+func (s *Status) Redact(opts RedactOptions) (*Status, error) {
+	if opts.Mode == RedactHash && len(opts.Key) == 0 {
+		return nil, ErrRedactKeyRequired
+	}
+	redacted := s.Clone()
+	if redacted == nil {
+		return nil, nil
+	}
+	redacted.Warnings = nil
+	for i := range redacted.Clients {
+		client := &redacted.Clients[i]
+		client.RealAddress = opts.address(client.RealAddress)
+		if opts.VirtualAddresses {
+			client.VirtualAddress = opts.address(client.VirtualAddress)
+			client.VirtualV6Address = opts.address(client.VirtualV6Address)
+		}
+		if opts.Usernames {
+			client.Username = opts.value(client.Username)
+		}
+		opts.values(client.Extra)
+		opts.values(client.Metadata)
+	}
+	for i := range redacted.Routes {
+		route := &redacted.Routes[i]
+		route.RealAddress = opts.address(route.RealAddress)
+		if opts.VirtualAddresses {
+			route.VirtualAddress = opts.address(route.VirtualAddress)
+		}
+	}
+	return redacted, nil
+}
+
+// address redacts an address as printed by OpenVPN
+func (opts RedactOptions) address(addr string) string {
+	if addr == "" || addr == undefined {
+		return addr
+	}
+	ip := parseAddressIP(addr)
+	if ip == nil {
+		return opts.value(addr)
+	}
+	if opts.Mode == RedactHash {
+		return opts.hash(ip.String())
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return fmt.Sprintf("%d.%d.%d.x", ip4[0], ip4[1], ip4[2])
+	}
+	network := ip.Mask(net.CIDRMask(48, 128)).String()
+	return strings.TrimSuffix(network, "::") + "::x"
+}
+
+// value redacts any other value
+func (opts RedactOptions) value(v string) string {
+	if v == "" || v == undefined {
+		return v
+	}
+	if opts.Mode == RedactHash {
+		return opts.hash(v)
+	}
+	return "x"
+}
+
+// values redacts the values of `m` in place
+func (opts RedactOptions) values(m map[string]string) {
+	for k, v := range m {
+		m[k] = opts.value(v)
+	}
+}
+
+func (opts RedactOptions) hash(v string) string {
+	mac := hmac.New(sha256.New, opts.Key)
+	mac.Write([]byte(v))
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
@@ -0,0 +1,68 @@
+package ovpnstats_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+func TestRedact(t *testing.T) {
+	status := &ovpnstats.Status{
+		Clients: []ovpnstats.ClientInfo{{
+			Name:           "alice",
+			RealAddress:    "203.0.113.5:1194",
+			VirtualAddress: "10.8.0.2",
+			Username:       "alice",
+			Extra:          map[string]string{"Remote": "203.0.113.5"},
+			Metadata:       map[string]string{"site": "198.51.100.1"},
+		}},
+		Warnings: []ovpnstats.ParseWarning{{Line: 3, Err: errors.New(`malformed line "CLIENT_LIST,bob,203.0.113.6:1194"`)}},
+	}
+	tests := []struct {
+		name    string
+		opts    ovpnstats.RedactOptions
+		wantErr error
+	}{
+		{name: "mask", opts: ovpnstats.RedactOptions{Mode: ovpnstats.RedactMask}},
+		{name: "hash", opts: ovpnstats.RedactOptions{Mode: ovpnstats.RedactHash, Key: []byte("secret")}},
+		{name: "hash without key", opts: ovpnstats.RedactOptions{Mode: ovpnstats.RedactHash}, wantErr: ovpnstats.ErrRedactKeyRequired},
+		{name: "hash with empty key", opts: ovpnstats.RedactOptions{Mode: ovpnstats.RedactHash, Key: []byte{}}, wantErr: ovpnstats.ErrRedactKeyRequired},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			redacted, err := status.Redact(test.opts)
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) || redacted != nil {
+					t.Fatalf("Redact() = %v, %v, want nil, %v", redacted, err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Redact: %v", err)
+			}
+			client := redacted.Clients[0]
+			if client.RealAddress == status.Clients[0].RealAddress {
+				t.Errorf("Real Address %q wasn't redacted", client.RealAddress)
+			}
+			if client.Extra["Remote"] == "203.0.113.5" || client.Metadata["site"] == "198.51.100.1" {
+				t.Errorf("Extra %v and Metadata %v weren't redacted", client.Extra, client.Metadata)
+			}
+			if len(redacted.Warnings) != 0 {
+				t.Errorf("Warnings %v weren't dropped", redacted.Warnings)
+			}
+			original := status.Clients[0]
+			if original.RealAddress != "203.0.113.5:1194" || original.Extra["Remote"] != "203.0.113.5" || original.Metadata["site"] != "198.51.100.1" || len(status.Warnings) != 1 {
+				t.Errorf("Redact modified the original Status")
+			}
+		})
+	}
+}
+
+func TestRedactNilStatus(t *testing.T) {
+	var status *ovpnstats.Status
+	redacted, err := status.Redact(ovpnstats.RedactOptions{})
+	if redacted != nil || err != nil {
+		t.Errorf("Redact() = %v, %v, want nil, nil", redacted, err)
+	}
+}
@@ -0,0 +1,47 @@
+package ovpnstats
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// formatBytes formats `n` bytes in human readable IEC units (e.g. "1.5 MiB")
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// RenderSummary writes an aligned text table of the clients in `s`
+// (name, real address, virtual address, received, sent and uptime at s.Now()) followed by a totals line.
+// A nil Status renders as an empty table
+func RenderSummary(w io.Writer, s *Status) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tREAL ADDRESS\tVIRTUAL ADDRESS\tRECEIVED\tSENT\tUPTIME")
+
+	now := s.Now()
+	var received, sent int64
+	for _, client := range s.clients() {
+		received = addBytes(received, client.BytesReceived)
+		sent = addBytes(sent, client.BytesSent)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			client.Name,
+			client.RealAddress,
+			client.VirtualAddress,
+			formatBytes(client.BytesReceived),
+			formatBytes(client.BytesSent),
+			client.ConnectedDuration(now).Truncate(time.Second),
+		)
+	}
+	fmt.Fprintf(tw, "TOTAL (%d clients)\t\t\t%s\t%s\n", s.NumClients(), formatBytes(received), formatBytes(sent))
+	return tw.Flush()
+}
@@ -0,0 +1,20 @@
+package ovpnstats_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+func TestRenderSummaryNilStatus(t *testing.T) {
+	var b bytes.Buffer
+	if err := ovpnstats.RenderSummary(&b, nil); err != nil {
+		t.Fatalf("RenderSummary: %v", err)
+	}
+	const want = "NAME               REAL ADDRESS  VIRTUAL ADDRESS  RECEIVED  SENT  UPTIME\n" +
+		"TOTAL (0 clients)                                 0 B       0 B\n"
+	if b.String() != want {
+		t.Errorf("RenderSummary(nil) = %q, want %q", b.String(), want)
+	}
+}
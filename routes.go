@@ -0,0 +1,182 @@
+package ovpnstats
+
+import "time"
+
+// AssignedAddresses returns the Virtual Addresses routed to the clients with Common Name `commonName`, in routing table order.
+// The ROUTING_TABLE is authoritative for address assignment: a client's CLIENT_LIST Virtual Address may lag behind, and even
+// be empty, while the routing table already holds the assigned one, so this helper only looks at the routing table
+func (s *Status) AssignedAddresses(commonName string) []string {
+	var addresses []string
+	for _, route := range s.Routes {
+		if route.CommonName == commonName {
+			addresses = append(addresses, route.VirtualAddress)
+		}
+	}
+	return addresses
+}
+
+// sameEndpoint reports whether `route` and `client` share the Common Name and the Real Address, ignoring a protocol prefix
+func sameEndpoint(route RoutingInfo, client ClientInfo) bool {
+	return newEndpoint(route.CommonName, route.RealAddress) == newEndpoint(client.Name, client.RealAddress)
+}
+
+// RoutesFor returns the routes of the session of `client`, in routing table order.
+// Routes are matched on both the Common Name and the Real Address, so that sessions sharing a Common Name
+// (duplicate-cn) get only their own routes, as long as they connect from different Real Addresses
+func (s *Status) RoutesFor(client ClientInfo) []RoutingInfo {
+	var routes []RoutingInfo
+	for _, route := range s.Routes {
+		if sameEndpoint(route, client) {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// ClientsFor returns the clients a route may belong to, matched on both the Common Name and the Real Address, in client list order.
+// The routing table doesn't hold a Client ID, so when several sessions share the Common Name and the Real Address
+// (e.g. duplicate-cn behind the same NAT and port) there's no way to tell them apart and all of them are returned.
+// The result is empty when the route's session isn't in the client list
+func (s *Status) ClientsFor(route RoutingInfo) []ClientInfo {
+	var clients []ClientInfo
+	for _, client := range s.Clients {
+		if sameEndpoint(route, client) {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
+// OldestRoute returns the route with the earliest Last Ref, i.e. the longest idle one.
+// Ties are broken by the lowest Virtual Address. ok is false when there are no routes
+func (s *Status) OldestRoute() (route RoutingInfo, ok bool) {
+	return s.findRoute(func(a, b RoutingInfo) bool { return a.LastRef.Before(b.LastRef) })
+}
+
+// NewestRoute returns the route with the latest Last Ref, i.e. the most recently referenced one.
+// Ties are broken by the lowest Virtual Address. ok is false when there are no routes
+func (s *Status) NewestRoute() (route RoutingInfo, ok bool) {
+	return s.findRoute(func(a, b RoutingInfo) bool { return a.LastRef.After(b.LastRef) })
+}
+
+// findRoute returns the route which goes first according to `first`, or to Virtual Address when it's a tie
+func (s *Status) findRoute(first func(a, b RoutingInfo) bool) (route RoutingInfo, ok bool) {
+	for i, candidate := range s.Routes {
+		if i == 0 || first(candidate, route) ||
+			(candidate.LastRef.Equal(route.LastRef) && candidate.VirtualAddress < route.VirtualAddress) {
+			route = candidate
+		}
+	}
+	return route, len(s.Routes) > 0
+}
+
+// Inconsistencies are the entries of each section of a status without counterpart in the other one
+type Inconsistencies struct {
+	// OrphanRoutes are the routes whose session, by Common Name and Real Address, isn't in the client list
+	OrphanRoutes []RoutingInfo
+	// UnroutedClients are the clients without any route
+	UnroutedClients []ClientInfo
+}
+
+// Empty reports whether both sections are consistent
+func (i Inconsistencies) Empty() bool {
+	return len(i.OrphanRoutes) == 0 && len(i.UnroutedClients) == 0
+}
+
+// endpoint is a Common Name and a Real Address without protocol prefix, which routes and clients are correlated by
+type endpoint struct {
+	commonName  string
+	realAddress string
+}
+
+func newEndpoint(commonName, realAddress string) endpoint {
+	_, realAddress = splitProtocol(realAddress)
+	return endpoint{commonName: commonName, realAddress: realAddress}
+}
+
+// Inconsistencies returns the routes and clients of `s` without counterpart in the other section, correlated like
+// RoutesFor and ClientsFor do. Either may come from a status file read while being rewritten, e.g. with a truncated
+// section, or from a ghost route OpenVPN hasn't expired yet. Clients still connecting (see IsConnecting) have no
+// route yet and aren't reported
+func (s *Status) Inconsistencies() Inconsistencies {
+	clients := make(map[endpoint]bool, len(s.Clients))
+	for _, client := range s.Clients {
+		clients[newEndpoint(client.Name, client.RealAddress)] = true
+	}
+	routed := make(map[endpoint]bool, len(s.Routes))
+	var result Inconsistencies
+	for _, route := range s.Routes {
+		key := newEndpoint(route.CommonName, route.RealAddress)
+		routed[key] = true
+		if !clients[key] {
+			result.OrphanRoutes = append(result.OrphanRoutes, route)
+		}
+	}
+	for _, client := range s.Clients {
+		if !client.IsConnecting() && !routed[newEndpoint(client.Name, client.RealAddress)] {
+			result.UnroutedClients = append(result.UnroutedClients, client)
+		}
+	}
+	return result
+}
+
+// DuplicateVirtualAddresses returns the clients sharing a Virtual Address, keyed by that address, which should never
+// happen and points to an address pool or assignment bug. Virtual Addresses and Virtual IPv6 Addresses are checked
+// separately, after parsing, so textual variants of the same IPv6 address are detected too.
+// Clients without a valid address of a family are ignored for it. The result is empty when there are no duplicates
+func (s *Status) DuplicateVirtualAddresses() map[string][]ClientInfo {
+	byAddress := make(map[string][]ClientInfo)
+	for _, kind := range []AddressKind{AddressVirtual, AddressVirtualV6} {
+		for _, client := range s.Clients {
+			if ip, ok := client.Address(kind); ok {
+				byAddress[ip.String()] = append(byAddress[ip.String()], client)
+			}
+		}
+	}
+	for address, clients := range byAddress {
+		if len(clients) < 2 {
+			delete(byAddress, address)
+		}
+	}
+	return byAddress
+}
+
+// LastActivity returns the latest Last Ref among the routes of the clients with Common Name `commonName`, which
+// approximates when any of its sessions last sent traffic, as the client list has no such column.
+// Routes with an unknown Last Ref are ignored. ok is false when no route of `commonName` has a known one
+func (s *Status) LastActivity(commonName string) (lastRef time.Time, ok bool) {
+	for _, route := range s.Routes {
+		if route.CommonName == commonName && route.HasLastRef() && route.LastRef.After(lastRef) {
+			lastRef, ok = route.LastRef, true
+		}
+	}
+	return lastRef, ok
+}
+
+// resolveVirtualAddresses fills the empty Virtual Addresses of the clients of `s` from their routes,
+// see WithResolveVirtualFromRoutes
+func (s *Status) resolveVirtualAddresses() {
+	sessions := make(map[endpoint]int, len(s.Clients))
+	for _, client := range s.Clients {
+		sessions[newEndpoint(client.Name, client.RealAddress)]++
+	}
+	assigned := make(map[endpoint]string)
+	for _, route := range s.Routes {
+		key := newEndpoint(route.CommonName, route.RealAddress)
+		if _, ok := assigned[key]; ok {
+			continue
+		}
+		if ip := route.VirtualIP(); ip != nil && ip.To4() != nil {
+			assigned[key] = route.VirtualAddress
+		}
+	}
+	for i, client := range s.Clients {
+		if client.VirtualAddress != "" || client.IsConnecting() {
+			continue
+		}
+		key := newEndpoint(client.Name, client.RealAddress)
+		if address, ok := assigned[key]; ok && sessions[key] == 1 {
+			s.Clients[i].VirtualAddress = address
+		}
+	}
+}
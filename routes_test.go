@@ -0,0 +1,137 @@
+package ovpnstats_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+func TestRoutesForDuplicateCommonNames(t *testing.T) {
+	const input = "HEADER,CLIENT_LIST,Common Name,Real Address,Virtual Address,Virtual IPv6 Address,Bytes Received,Bytes Sent,Connected Since,Connected Since (time_t),Username,Client ID,Peer ID,Data Channel Cipher\n" +
+		"CLIENT_LIST,shared,203.0.113.5:1194,10.8.0.2,,1,2,2021-03-01 09:00:00,1614589200,UNDEF,0,0,AES-256-GCM\n" +
+		"CLIENT_LIST,shared,198.51.100.7:1194,10.8.0.3,,3,4,2021-03-01 09:30:00,1614591000,UNDEF,1,1,AES-256-GCM\n" +
+		"CLIENT_LIST,shared,192.0.2.9:40000,10.8.0.4,,5,6,2021-03-01 09:40:00,1614591600,UNDEF,2,2,AES-256-GCM\n" +
+		"CLIENT_LIST,shared,192.0.2.9:40000,10.8.0.5,,7,8,2021-03-01 09:50:00,1614592200,UNDEF,3,3,AES-256-GCM\n" +
+		"HEADER,ROUTING_TABLE,Virtual Address,Common Name,Real Address,Last Ref,Last Ref (time_t)\n" +
+		"ROUTING_TABLE,10.8.0.3,shared,198.51.100.7:1194,2021-03-01 09:59:00,1614592740\n" +
+		"ROUTING_TABLE,10.8.0.2,shared,203.0.113.5:1194,2021-03-01 09:59:00,1614592740\n" +
+		"ROUTING_TABLE,10.8.0.4,shared,192.0.2.9:40000,2021-03-01 09:59:00,1614592740\n" +
+		"ROUTING_TABLE,10.8.0.6,shared,udp4:198.51.100.7:1194,2021-03-01 09:59:00,1614592740\n" +
+		"END\n"
+	status := mustParse(t, input)
+
+	tests := []struct {
+		name        string
+		client      ovpnstats.ClientInfo
+		wantRoutes  []string
+		wantClients []int
+	}{
+		{name: "first session", client: status.Clients[0], wantRoutes: []string{"10.8.0.2"}, wantClients: []int{0}},
+		{name: "second session with protocol prefixed route", client: status.Clients[1], wantRoutes: []string{"10.8.0.3", "10.8.0.6"}, wantClients: []int{1}},
+		{name: "sessions sharing the Real Address", client: status.Clients[2], wantRoutes: []string{"10.8.0.4"}, wantClients: []int{2, 3}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			routes := status.RoutesFor(test.client)
+			var addresses []string
+			for _, route := range routes {
+				addresses = append(addresses, route.VirtualAddress)
+			}
+			if !reflect.DeepEqual(addresses, test.wantRoutes) {
+				t.Fatalf("RoutesFor gave %v, want %v", addresses, test.wantRoutes)
+			}
+			clients := status.ClientsFor(routes[0])
+			var ids []int
+			for _, client := range clients {
+				ids = append(ids, client.ClientID)
+			}
+			if !reflect.DeepEqual(ids, test.wantClients) {
+				t.Errorf("ClientsFor gave Client IDs %v, want %v", ids, test.wantClients)
+			}
+		})
+	}
+}
+
+func TestWithResolveVirtualFromRoutes(t *testing.T) {
+	const header = "HEADER,CLIENT_LIST,Common Name,Real Address,Virtual Address,Virtual IPv6 Address,Bytes Received,Bytes Sent,Connected Since,Connected Since (time_t),Username,Client ID,Peer ID,Data Channel Cipher\n"
+	const routesHeader = "HEADER,ROUTING_TABLE,Virtual Address,Common Name,Real Address,Last Ref,Last Ref (time_t)\n"
+	tests := []struct {
+		name    string
+		clients string
+		routes  string
+		want    []string
+	}{
+		{
+			name:    "empty Virtual Address",
+			clients: "CLIENT_LIST,alice,203.0.113.5:1194,,,1,2,2021-03-01 09:00:00,1614589200,UNDEF,0,0,AES-256-GCM\n",
+			routes:  "ROUTING_TABLE,10.8.0.2,alice,203.0.113.5:1194,2021-03-01 09:59:00,1614592740\n",
+			want:    []string{"10.8.0.2"},
+		},
+		{
+			name:    "known Virtual Address kept",
+			clients: "CLIENT_LIST,alice,203.0.113.5:1194,10.8.0.9,,1,2,2021-03-01 09:00:00,1614589200,UNDEF,0,0,AES-256-GCM\n",
+			routes:  "ROUTING_TABLE,10.8.0.2,alice,203.0.113.5:1194,2021-03-01 09:59:00,1614592740\n",
+			want:    []string{"10.8.0.9"},
+		},
+		{
+			name:    "subnet and IPv6 routes skipped",
+			clients: "CLIENT_LIST,alice,203.0.113.5:1194,,,1,2,2021-03-01 09:00:00,1614589200,UNDEF,0,0,AES-256-GCM\n",
+			routes: "ROUTING_TABLE,192.168.1.0/24,alice,203.0.113.5:1194,2021-03-01 09:59:00,1614592740\n" +
+				"ROUTING_TABLE,fd00::2,alice,203.0.113.5:1194,2021-03-01 09:59:00,1614592740\n" +
+				"ROUTING_TABLE,10.8.0.2,alice,203.0.113.5:1194,2021-03-01 09:59:00,1614592740\n",
+			want: []string{"10.8.0.2"},
+		},
+		{
+			name:    "IPv6 route only",
+			clients: "CLIENT_LIST,alice,203.0.113.5:1194,,,1,2,2021-03-01 09:00:00,1614589200,UNDEF,0,0,AES-256-GCM\n",
+			routes:  "ROUTING_TABLE,fd00::2,alice,203.0.113.5:1194,2021-03-01 09:59:00,1614592740\n",
+			want:    []string{""},
+		},
+		{
+			name:    "other Real Address",
+			clients: "CLIENT_LIST,alice,203.0.113.5:1194,,,1,2,2021-03-01 09:00:00,1614589200,UNDEF,0,0,AES-256-GCM\n",
+			routes:  "ROUTING_TABLE,10.8.0.2,alice,198.51.100.7:40000,2021-03-01 09:59:00,1614592740\n",
+			want:    []string{""},
+		},
+		{
+			name:    "Protocol prefix",
+			clients: "CLIENT_LIST,alice,udp4:203.0.113.5:1194,,,1,2,2021-03-01 09:00:00,1614589200,UNDEF,0,0,AES-256-GCM\n",
+			routes:  "ROUTING_TABLE,10.8.0.2,alice,203.0.113.5:1194,2021-03-01 09:59:00,1614592740\n",
+			want:    []string{"10.8.0.2"},
+		},
+		{
+			name: "duplicate-cn sessions behind the same address",
+			clients: "CLIENT_LIST,shared,203.0.113.5:1194,,,1,2,2021-03-01 09:00:00,1614589200,UNDEF,0,0,AES-256-GCM\n" +
+				"CLIENT_LIST,shared,203.0.113.5:1194,,,3,4,2021-03-01 09:30:00,1614591000,UNDEF,1,1,AES-256-GCM\n",
+			routes: "ROUTING_TABLE,10.8.0.2,shared,203.0.113.5:1194,2021-03-01 09:59:00,1614592740\n" +
+				"ROUTING_TABLE,10.8.0.3,shared,203.0.113.5:1194,2021-03-01 09:59:00,1614592740\n",
+			want: []string{"", ""},
+		},
+		{
+			name:    "connecting client",
+			clients: "CLIENT_LIST,alice,UNDEF,,,0,0,2021-03-01 09:00:00,1614589200,UNDEF,0,0,\n",
+			routes:  "ROUTING_TABLE,10.8.0.2,alice,UNDEF,2021-03-01 09:59:00,1614592740\n",
+			want:    []string{""},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input := header + test.clients + routesHeader + test.routes + "END\n"
+			status := mustParse(t, input, ovpnstats.WithResolveVirtualFromRoutes())
+			var got []string
+			for _, client := range status.Clients {
+				got = append(got, client.VirtualAddress)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Virtual Addresses = %q, want %q", got, test.want)
+			}
+			for _, client := range mustParse(t, input).Clients {
+				if client.VirtualAddress != "" && !strings.Contains(test.clients, ","+client.VirtualAddress+",") {
+					t.Errorf("Virtual Address %q resolved without WithResolveVirtualFromRoutes", client.VirtualAddress)
+				}
+			}
+		})
+	}
+}
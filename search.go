@@ -0,0 +1,33 @@
+package ovpnstats
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SearchClients returns the clients whose Common Name matches `pattern`, in client list order.
+// When `regex` is false, `pattern` matches Common Names containing it, ignoring case, so "" matches every client.
+// When `regex` is true, `pattern` is a regexp (see regexp/syntax) matching anywhere in the Common Name unless anchored
+// with ^ and $, and case sensitive unless it starts with (?i). An invalid regexp is returned as an error
+func (s *Status) SearchClients(pattern string, regex bool) ([]ClientInfo, error) {
+	var match func(name string) bool
+	if regex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("ovpnstats: invalid search pattern: %w", err)
+		}
+		match = re.MatchString
+	} else {
+		pattern = strings.ToLower(pattern)
+		match = func(name string) bool { return strings.Contains(strings.ToLower(name), pattern) }
+	}
+
+	var clients []ClientInfo
+	for _, client := range s.Clients {
+		if match(client.Name) {
+			clients = append(clients, client)
+		}
+	}
+	return clients, nil
+}
@@ -0,0 +1,123 @@
+package ovpnstats
+
+// PeerIDChange describes a Peer ID which refers to a different session in a later snapshot
+type PeerIDChange struct {
+	Old ClientInfo
+	New ClientInfo
+}
+
+// sameSession reports whether `a` and `b` describe the same connection of the same client
+func sameSession(a, b ClientInfo) bool {
+	return a.Name == b.Name && a.ConnectedSince.Equal(b.ConnectedSince)
+}
+
+// PeerIDChurn returns, keyed by Peer ID, the clients of `curr` whose Peer ID was already in use in `prev`
+// by a different session (different Common Name or Connected Since).
+// OpenVPN hands out Peer IDs of disconnected clients again, so a reused ID must not be taken for the same long-lived client.
+// A nil snapshot, e.g. before the first poll, has no clients
+func PeerIDChurn(prev, curr *Status) map[int]PeerIDChange {
+	previous := make(map[int]ClientInfo, prev.NumClients())
+	for _, client := range prev.clients() {
+		previous[client.PeerID] = client
+	}
+
+	churn := make(map[int]PeerIDChange)
+	for _, client := range curr.clients() {
+		old, ok := previous[client.PeerID]
+		if !ok || sameSession(old, client) {
+			continue
+		}
+		churn[client.PeerID] = PeerIDChange{Old: old, New: client}
+	}
+	return churn
+}
+
+// sessionKey identifies a single connection of a client across snapshots
+type sessionKey struct {
+	name           string
+	clientID       int
+	connectedSince int64
+}
+
+// session returns the identity of the client's connection: its Common Name, Client ID and Connected Since
+func (c ClientInfo) session() sessionKey {
+	return sessionKey{name: c.Name, clientID: c.ClientID, connectedSince: c.ConnectedSinceUnix()}
+}
+
+// Roam describes a session whose Real Address changed between two snapshots
+type Roam struct {
+	Name    string
+	OldAddr string
+	NewAddr string
+}
+
+// RoamingClients returns the sessions of `curr` which were already connected in `prev` from a different Real Address,
+// e.g. a mobile client switching between wifi and cellular. Sessions are matched by Common Name, Client ID and
+// Connected Since, so a reconnection from a new address is a new session and not reported. A nil snapshot has no clients
+func RoamingClients(prev, curr *Status) []Roam {
+	previous := make(map[sessionKey]ClientInfo, prev.NumClients())
+	for _, client := range prev.clients() {
+		previous[client.session()] = client
+	}
+
+	var roams []Roam
+	for _, client := range curr.clients() {
+		old, ok := previous[client.session()]
+		if !ok || old.RealAddress == client.RealAddress {
+			continue
+		}
+		roams = append(roams, Roam{Name: client.Name, OldAddr: old.RealAddress, NewAddr: client.RealAddress})
+	}
+	return roams
+}
+
+// ReconnectTracker counts the reconnections of each Common Name across successive snapshots.
+// A session (same Common Name, Client ID and Connected Since, regardless of its Peer ID, which OpenVPN reuses) not seen
+// in any earlier snapshot is a reconnection when its Common Name was already seen, with the same Client ID or not,
+// and a brand-new client otherwise. The sessions of the first snapshot are all brand-new.
+// Every session seen is kept until Reset, so a session missing from some snapshots, e.g. truncated reads, isn't
+// counted again when it shows up: as Connected Since is part of its identity, it's the same session and not a
+// reconnection. Call Reset, e.g. every counting window, to bound the memory used by long running trackers.
+// With duplicate-cn, a second concurrent session of a known Common Name is counted as a reconnection too.
+// The zero value is ready to use. It's not safe for concurrent use
+type ReconnectTracker struct {
+	sessions map[sessionKey]bool
+	known    map[string]bool
+	counts   map[string]int
+}
+
+// Update counts the reconnections in `s`, which must be more recent than the previous one given
+func (t *ReconnectTracker) Update(s *Status) {
+	if t.known == nil {
+		t.sessions = make(map[sessionKey]bool)
+		t.known = make(map[string]bool)
+		t.counts = make(map[string]int)
+	}
+	for _, client := range s.clients() {
+		key := client.session()
+		if t.sessions[key] {
+			continue
+		}
+		t.sessions[key] = true
+		if t.known[client.Name] {
+			t.counts[client.Name]++
+		}
+	}
+	for _, client := range s.clients() {
+		t.known[client.Name] = true
+	}
+}
+
+// Counts returns the number of reconnections of each Common Name which reconnected at least once
+func (t *ReconnectTracker) Counts() map[string]int {
+	counts := make(map[string]int, len(t.counts))
+	for name, count := range t.counts {
+		counts[name] = count
+	}
+	return counts
+}
+
+// Reset forgets every session and count, e.g. to start a new counting window
+func (t *ReconnectTracker) Reset() {
+	*t = ReconnectTracker{}
+}
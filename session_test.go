@@ -0,0 +1,186 @@
+package ovpnstats_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+// session returns a client connected since `since` (a time_t) with Peer ID `peerID`
+func session(name string, clientID, peerID int, since int64) ovpnstats.ClientInfo {
+	return ovpnstats.ClientInfo{Name: name, ClientID: clientID, PeerID: peerID, ConnectedSince: time.Unix(since, 0)}
+}
+
+func TestPeerIDChurn(t *testing.T) {
+	alice := session("alice", 0, 0, 1614589200)
+	bob := session("bob", 1, 0, 1614591000)
+	tests := []struct {
+		name       string
+		prev, curr *ovpnstats.Status
+		want       map[int]ovpnstats.PeerIDChange
+	}{
+		{name: "nil previous snapshot", curr: &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{alice}}, want: map[int]ovpnstats.PeerIDChange{}},
+		{name: "nil current snapshot", prev: &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{alice}}, want: map[int]ovpnstats.PeerIDChange{}},
+		{
+			name: "same session",
+			prev: &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{alice}},
+			curr: &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{alice}},
+			want: map[int]ovpnstats.PeerIDChange{},
+		},
+		{
+			name: "Peer ID reused by another client",
+			prev: &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{alice}},
+			curr: &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{bob}},
+			want: map[int]ovpnstats.PeerIDChange{0: {Old: alice, New: bob}},
+		},
+		{
+			name: "Peer ID reused by a reconnection",
+			prev: &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{alice}},
+			curr: &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{session("alice", 2, 0, 1614592800)}},
+			want: map[int]ovpnstats.PeerIDChange{0: {Old: alice, New: session("alice", 2, 0, 1614592800)}},
+		},
+		{
+			name: "new Peer ID",
+			prev: &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{alice}},
+			curr: &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{alice, session("bob", 1, 1, 1614591000)}},
+			want: map[int]ovpnstats.PeerIDChange{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ovpnstats.PeerIDChurn(test.prev, test.curr); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("PeerIDChurn() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRoamingClients(t *testing.T) {
+	alice := ovpnstats.ClientInfo{Name: "alice", RealAddress: "203.0.113.5:1194", ConnectedSince: time.Unix(1614589200, 0)}
+	roamed := alice
+	roamed.RealAddress = "198.51.100.7:40000"
+	reconnected := roamed
+	reconnected.ClientID, reconnected.ConnectedSince = 1, time.Unix(1614592800, 0)
+	bob := ovpnstats.ClientInfo{Name: "bob", ClientID: 2, RealAddress: "[2001:db8::1]:50123", ConnectedSince: time.Unix(1614591000, 0)}
+	tests := []struct {
+		name       string
+		prev, curr *ovpnstats.Status
+		want       []ovpnstats.Roam
+	}{
+		{name: "nil previous snapshot", curr: &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{alice}}},
+		{
+			name: "same address",
+			prev: &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{alice, bob}},
+			curr: &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{bob, alice}},
+		},
+		{
+			name: "roamed",
+			prev: &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{alice, bob}},
+			curr: &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{roamed, bob}},
+			want: []ovpnstats.Roam{{Name: "alice", OldAddr: "203.0.113.5:1194", NewAddr: "198.51.100.7:40000"}},
+		},
+		{
+			name: "reconnected from another address",
+			prev: &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{alice, bob}},
+			curr: &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{reconnected, bob}},
+		},
+		{
+			name: "new client",
+			prev: &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{alice}},
+			curr: &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{alice, bob}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ovpnstats.RoamingClients(test.prev, test.curr); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("RoamingClients() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReconnectTracker(t *testing.T) {
+	alice := session("alice", 0, 0, 1614589200)
+	aliceReconnected := session("alice", 2, 0, 1614592800)
+	aliceSameClientID := session("alice", 0, 0, 1614592800)
+	bob := session("bob", 1, 1, 1614591000)
+	tests := []struct {
+		name      string
+		snapshots []*ovpnstats.Status
+		want      map[string]int
+	}{
+		{name: "first snapshot is brand-new", snapshots: []*ovpnstats.Status{snapshot(1614592800, alice, bob)}, want: map[string]int{}},
+		{
+			name:      "same sessions",
+			snapshots: []*ovpnstats.Status{snapshot(1614592800, alice, bob), snapshot(1614592860, alice, bob)},
+			want:      map[string]int{},
+		},
+		{
+			name:      "Peer ID change is the same session",
+			snapshots: []*ovpnstats.Status{snapshot(1614592800, alice), snapshot(1614592860, session("alice", 0, 5, 1614589200))},
+			want:      map[string]int{},
+		},
+		{
+			name:      "new client",
+			snapshots: []*ovpnstats.Status{snapshot(1614592800, alice), snapshot(1614592860, alice, bob)},
+			want:      map[string]int{},
+		},
+		{
+			name:      "reconnection",
+			snapshots: []*ovpnstats.Status{snapshot(1614592740, alice), snapshot(1614592800, aliceReconnected)},
+			want:      map[string]int{"alice": 1},
+		},
+		{
+			name:      "reconnection with the same Client ID",
+			snapshots: []*ovpnstats.Status{snapshot(1614592740, alice), snapshot(1614592800, aliceSameClientID)},
+			want:      map[string]int{"alice": 1},
+		},
+		{
+			name:      "reconnection after a gap",
+			snapshots: []*ovpnstats.Status{snapshot(1614592680, alice), snapshot(1614592740), snapshot(1614592800, aliceReconnected)},
+			want:      map[string]int{"alice": 1},
+		},
+		{
+			name:      "session back after a gap",
+			snapshots: []*ovpnstats.Status{snapshot(1614592680, alice, bob), snapshot(1614592740, bob), snapshot(1614592800, alice, bob)},
+			want:      map[string]int{},
+		},
+		{
+			name:      "session back after a nil snapshot",
+			snapshots: []*ovpnstats.Status{snapshot(1614592680, alice), nil, snapshot(1614592800, alice)},
+			want:      map[string]int{},
+		},
+		{
+			name:      "duplicate-cn",
+			snapshots: []*ovpnstats.Status{snapshot(1614592740, alice), snapshot(1614592800, alice, aliceReconnected)},
+			want:      map[string]int{"alice": 1},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var tracker ovpnstats.ReconnectTracker
+			for _, s := range test.snapshots {
+				tracker.Update(s)
+			}
+			if got := tracker.Counts(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Counts() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReconnectTrackerReset(t *testing.T) {
+	var tracker ovpnstats.ReconnectTracker
+	tracker.Update(snapshot(1614592740, session("alice", 0, 0, 1614589200)))
+	tracker.Update(snapshot(1614592800, session("alice", 1, 0, 1614592800)))
+	tracker.Reset()
+	if got := tracker.Counts(); len(got) != 0 {
+		t.Errorf("Counts() = %v after Reset, want none", got)
+	}
+	tracker.Update(snapshot(1614592860, session("alice", 2, 0, 1614592860)))
+	if got := tracker.Counts(); len(got) != 0 {
+		t.Errorf("Counts() = %v after Reset and a first snapshot, want none", got)
+	}
+}
@@ -0,0 +1,34 @@
+package ovpnstats
+
+import (
+	"math"
+	"sort"
+)
+
+// BytePercentiles returns the requested percentiles (between 0 and 100, e.g. 50 for the median and 95) of the
+// total bytes (received plus sent) of the clients in `s`, keyed by the requested percentile.
+// It uses the nearest-rank method: the p-th percentile is the smallest value such that at least p% of the
+// clients are at or below it, so it's always an actual client's total. Percentiles out of range are clamped.
+// The result is empty when there are no clients
+func (s *Status) BytePercentiles(ps ...float64) map[float64]int64 {
+	result := make(map[float64]int64, len(ps))
+	if len(s.Clients) == 0 {
+		return result
+	}
+	totals := make([]int64, len(s.Clients))
+	for i, client := range s.Clients {
+		totals[i] = addBytes(client.BytesReceived, client.BytesSent)
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i] < totals[j] })
+
+	for _, p := range ps {
+		rank := int(math.Ceil(p / 100 * float64(len(totals))))
+		if rank < 1 {
+			rank = 1
+		} else if rank > len(totals) {
+			rank = len(totals)
+		}
+		result[p] = totals[rank-1]
+	}
+	return result
+}
@@ -0,0 +1,142 @@
+package ovpnstats
+
+import (
+	"fmt"
+	"time"
+)
+
+// Status represents a whole openvpn-status.log snapshot
+type Status struct {
+	// Version is the status file format: 2 (comma separated) or 3 (tab separated).
+	// It's 0 when nothing was parsed, which WriteStatus treats as 2
+	Version int
+	// Title is the TITLE line, i.e. the OpenVPN version string
+	Title string
+	// UpdatedAt is the TIME line, when the file was written
+	UpdatedAt time.Time
+	Clients   []ClientInfo
+	Routes    []RoutingInfo
+	// GlobalStats are the GLOBAL_STATS lines keyed by name, e.g. "Max bcast/mcast queue length"
+	GlobalStats map[string]string
+	// Warnings are the records skipped by a WithLenient parse, in input order
+	Warnings []ParseWarning
+	// Clock is the time source of the helpers needing the current time, set with WithClock. Nil uses the system clock.
+	// It's honored by Now, ConnectedDuration, ClientsConnectedFor and RenderSummary, while the helpers taking the time
+	// as an argument, like ClientInfo.ConnectedDuration and LongLivedClients, use the one given
+	Clock Clock
+}
+
+// Clock provides the current time, e.g. a fixed one in tests
+type Clock interface {
+	Now() time.Time
+}
+
+// Now returns the current time according to s.Clock, or the system clock when `s` or its Clock is nil
+func (s *Status) Now() time.Time {
+	if s == nil || s.Clock == nil {
+		return time.Now()
+	}
+	return s.Clock.Now()
+}
+
+// ParseWarning is a record skipped by a WithLenient parse
+type ParseWarning struct {
+	// Line is the line number of the record, from 1
+	Line int
+	// Err is why the record couldn't be parsed
+	Err error
+}
+
+func (w ParseWarning) Error() string {
+	return fmt.Sprintf("line %d: %v", w.Line, w.Err)
+}
+
+// Unwrap returns w.Err
+func (w ParseWarning) Unwrap() error {
+	return w.Err
+}
+
+// Clone returns a deep copy of `s` which shares no memory with it
+func (s *Status) Clone() *Status {
+	if s == nil {
+		return nil
+	}
+	clone := &Status{Version: s.Version, Title: s.Title, UpdatedAt: s.UpdatedAt, Clock: s.Clock}
+	if s.Clients != nil {
+		clone.Clients = make([]ClientInfo, len(s.Clients))
+		for i, client := range s.Clients {
+			clone.Clients[i] = client.clone()
+		}
+	}
+	if s.Routes != nil {
+		clone.Routes = make([]RoutingInfo, len(s.Routes))
+		copy(clone.Routes, s.Routes)
+	}
+	if s.Warnings != nil {
+		clone.Warnings = make([]ParseWarning, len(s.Warnings))
+		copy(clone.Warnings, s.Warnings)
+	}
+	if s.GlobalStats != nil {
+		clone.GlobalStats = make(map[string]string, len(s.GlobalStats))
+		for name, value := range s.GlobalStats {
+			clone.GlobalStats[name] = value
+		}
+	}
+	return clone
+}
+
+// clone returns a copy of `c` which shares no memory with it
+func (c ClientInfo) clone() ClientInfo {
+	c.Extra = copyStrings(c.Extra)
+	c.Metadata = copyStrings(c.Metadata)
+	return c
+}
+
+// copyStrings returns a copy of `m`, nil when `m` is nil
+func copyStrings(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// NumClients returns the number of CLIENT_LIST entries of `s`
+func (s *Status) NumClients() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.Clients)
+}
+
+// clients returns the CLIENT_LIST entries of `s`, none when `s` is nil
+func (s *Status) clients() []ClientInfo {
+	if s == nil {
+		return nil
+	}
+	return s.Clients
+}
+
+// routes returns the ROUTING_TABLE entries of `s`, none when `s` is nil
+func (s *Status) routes() []RoutingInfo {
+	if s == nil {
+		return nil
+	}
+	return s.Routes
+}
+
+// NumRoutes returns the number of ROUTING_TABLE entries of `s`
+func (s *Status) NumRoutes() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.Routes)
+}
+
+// Empty reports whether `s` has neither clients nor routes. A nil Status is empty
+func (s *Status) Empty() bool {
+	return s.NumClients() == 0 && s.NumRoutes() == 0
+}
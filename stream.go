@@ -0,0 +1,108 @@
+package ovpnstats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// StreamStatusBlocks extracts the status file dumps embedded in `r`, e.g. a log combining them with syslog lines,
+// and sends each of them, parsed with `opts`, on the returned Status channel as soon as its END line is read.
+// A block starts with a TITLE, TIME or HEADER line and ends with an END line. Lines which aren't status file
+// records, inside or outside blocks, are skipped, and so is a block interrupted by the start of another one.
+// WithMaxBytes bounds the size of each block, counting its records and their line breaks, rather than that of the whole
+// stream, which may be endless. Parsing stops at the first error, which is sent on the error channel, then both channels are closed.
+// The Status channel must be drained until closed, unless the parse is cancelled with WithContext
+func StreamStatusBlocks(r io.Reader, opts ...Option) (<-chan *Status, <-chan error) {
+	o := newOptions(opts)
+	statuses := make(chan *Status)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(statuses)
+		if err := streamStatusBlocks(r, o, statuses); err != nil {
+			errs <- err
+		}
+	}()
+	return statuses, errs
+}
+
+// streamStatusBlocks sends the status blocks of `r` on `statuses`
+func streamStatusBlocks(r io.Reader, o *options, statuses chan<- *Status) error {
+	var done <-chan struct{}
+	if o.ctx != nil {
+		done = o.ctx.Done()
+	}
+	in := &inputReader{r: r, ctx: o.ctx}
+	scanner := bufio.NewScanner(in)
+	var p *parser
+	// size is the number of bytes of the current block
+	var size int64
+	for scanner.Scan() {
+		line := scanner.Text()
+		version := 0
+		if p != nil {
+			version = p.status.Version
+		}
+		recordType, ok := blockRecordType(line, version)
+		if !ok {
+			continue
+		}
+		if p != nil && interruptsBlock(recordType, p.status) {
+			o.logger.Printf("ovpnstats: skipped status block without END line")
+			p = nil
+		}
+		if p == nil {
+			if recordType != "TITLE" && recordType != "TIME" && recordType != "HEADER" {
+				continue
+			}
+			p = newParser(o)
+			size = 0
+		}
+		size += int64(len(line)) + 1
+		if o.maxBytes > 0 && size > o.maxBytes {
+			return fmt.Errorf("%w: status block of more than %d bytes", ErrInputTooLarge, o.maxBytes)
+		}
+		if err := p.parseLine(line); err != nil {
+			return err
+		}
+		if p.ended {
+			select {
+			case statuses <- p.status:
+			case <-done:
+				return o.ctx.Err()
+			}
+			p = nil
+		}
+	}
+	if err := in.err(); err != nil {
+		return err
+	}
+	return scanner.Err()
+}
+
+// blockRecordType returns the record type of `line` in a status file of `version`, or of the version detected
+// from `line` when 0. ok is false when `line` isn't a status file record
+func blockRecordType(line string, version int) (recordType string, ok bool) {
+	if version == 0 {
+		var err error
+		if version, err = detectVersion(line, nil); err != nil {
+			return "", false
+		}
+	}
+	recordType = strings.SplitN(line, separator(version), 2)[0]
+	return recordType, knownRecordTypes[recordType]
+}
+
+// interruptsBlock reports whether a `recordType` line starts a new block rather than continuing `s`:
+// TITLE always comes first, and TIME comes before any record but HEADER
+func interruptsBlock(recordType string, s *Status) bool {
+	switch recordType {
+	case "TITLE":
+		return true
+	case "TIME":
+		return !s.UpdatedAt.IsZero() || len(s.Clients)+len(s.Routes)+len(s.GlobalStats) > 0
+	}
+	return false
+}
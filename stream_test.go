@@ -0,0 +1,48 @@
+package ovpnstats_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+func TestStreamStatusBlocks(t *testing.T) {
+	const block = "TITLE,OpenVPN 2.6.8 x86_64-pc-linux-gnu\n" +
+		"TIME,2021-03-01 10:00:00,1614592800\n" +
+		"HEADER,CLIENT_LIST,Common Name,Real Address,Virtual Address,Virtual IPv6 Address,Bytes Received,Bytes Sent,Connected Since,Connected Since (time_t),Username,Client ID,Peer ID,Data Channel Cipher\n" +
+		"Mar  1 10:00:00 gw openvpn[42]: alice/203.0.113.5:1194 MULTI: primary virtual IP for alice: 10.8.0.2\n" +
+		"CLIENT_LIST,alice,203.0.113.5:1194,10.8.0.2,,1,2,2021-03-01 09:00:00,1614589200,UNDEF,0,0,AES-256-GCM\n" +
+		"END\n"
+	const noise = "Mar  1 10:00:01 gw sshd[7]: Accepted publickey for root\n"
+	input := noise + block + noise + noise + block
+	tests := []struct {
+		name       string
+		opts       []ovpnstats.Option
+		wantBlocks int
+		wantErr    error
+	}{
+		{name: "unlimited", wantBlocks: 2},
+		{name: "blocks within WithMaxBytes", opts: []ovpnstats.Option{ovpnstats.WithMaxBytes(int64(len(block)))}, wantBlocks: 2},
+		{name: "blocks beyond WithMaxBytes", opts: []ovpnstats.Option{ovpnstats.WithMaxBytes(200)}, wantErr: ovpnstats.ErrInputTooLarge},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statuses, errs := ovpnstats.StreamStatusBlocks(strings.NewReader(input), test.opts...)
+			var blocks int
+			for status := range statuses {
+				blocks++
+				if len(status.Clients) != 1 || status.Clients[0].Name != "alice" {
+					t.Errorf("block %d has clients %+v, want alice alone", blocks, status.Clients)
+				}
+			}
+			if err := <-errs; !errors.Is(err, test.wantErr) {
+				t.Fatalf("StreamStatusBlocks error = %v, want %v", err, test.wantErr)
+			}
+			if blocks != test.wantBlocks {
+				t.Errorf("got %d blocks, want %d", blocks, test.wantBlocks)
+			}
+		})
+	}
+}
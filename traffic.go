@@ -0,0 +1,87 @@
+package ovpnstats
+
+import "math"
+
+// Traffic is an amount of received (Rx) and sent (Tx) bytes
+type Traffic struct {
+	Rx int64
+	Tx int64
+}
+
+// AccumulatedTraffic accumulates the traffic of clients across successive snapshots, e.g. for usage accounting.
+// A session (same Common Name, Client ID and Connected Since) seen again only adds the growth of its counters,
+// while a new session, i.e. a reconnection whose counters start again from zero, adds its counters in full.
+// The last counters of every session are kept until Reset, so a session missing from some snapshots, e.g. truncated
+// reads, only adds its growth since it was last seen when it shows up again: as Connected Since is part of its
+// identity, it's the same session and not a reconnection. Call Reset, e.g. every billing period, to bound the memory
+// used by long running trackers.
+// The zero value is ready to use. It's not safe for concurrent use
+type AccumulatedTraffic struct {
+	last       map[sessionKey]Traffic
+	byName     map[string]Traffic
+	byUsername map[string]Traffic
+}
+
+// Update accumulates the traffic of the clients in `s`, which must be more recent than the previous one given
+func (t *AccumulatedTraffic) Update(s *Status) {
+	if t.byName == nil {
+		t.last = make(map[sessionKey]Traffic)
+		t.byName = make(map[string]Traffic)
+		t.byUsername = make(map[string]Traffic)
+	}
+	for _, client := range s.Clients {
+		key := client.session()
+		counters := Traffic{Rx: client.BytesReceived, Tx: client.BytesSent}
+		delta := counters
+		if previous, ok := t.last[key]; ok {
+			delta = Traffic{Rx: counterDelta(previous.Rx, counters.Rx), Tx: counterDelta(previous.Tx, counters.Tx)}
+		}
+		t.last[key] = counters
+		t.byName[client.Name] = t.byName[client.Name].add(delta)
+		t.byUsername[client.Username] = t.byUsername[client.Username].add(delta)
+	}
+}
+
+// ByCommonName returns the accumulated traffic keyed by Common Name
+func (t *AccumulatedTraffic) ByCommonName() map[string]Traffic {
+	return copyTraffic(t.byName)
+}
+
+// ByUsername returns the accumulated traffic keyed by Username, as printed by OpenVPN ("UNDEF" without one)
+func (t *AccumulatedTraffic) ByUsername() map[string]Traffic {
+	return copyTraffic(t.byUsername)
+}
+
+// Reset forgets every session and accumulated traffic, e.g. to start a new billing period
+func (t *AccumulatedTraffic) Reset() {
+	*t = AccumulatedTraffic{}
+}
+
+func (t Traffic) add(other Traffic) Traffic {
+	return Traffic{Rx: addBytes(t.Rx, other.Rx), Tx: addBytes(t.Tx, other.Tx)}
+}
+
+// addBytes adds the non-negative byte counts `a` and `b`, saturating to math.MaxInt64 like parsed counters do
+func addBytes(a, b int64) int64 {
+	if a > math.MaxInt64-b {
+		return math.MaxInt64
+	}
+	return a + b
+}
+
+// counterDelta returns the growth of a counter from `previous` to `current`.
+// A counter going backwards has been reset and its whole current value is new
+func counterDelta(previous, current int64) int64 {
+	if current < previous {
+		return current
+	}
+	return current - previous
+}
+
+func copyTraffic(m map[string]Traffic) map[string]Traffic {
+	c := make(map[string]Traffic, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
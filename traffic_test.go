@@ -0,0 +1,59 @@
+package ovpnstats_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+func TestAccumulatedTrafficUpdate(t *testing.T) {
+	since := time.Unix(1614592800, 0)
+	client := func(rx, tx int64, connectedSince time.Time) ovpnstats.ClientInfo {
+		return ovpnstats.ClientInfo{Name: "alice", Username: "alice", ClientID: 1, BytesReceived: rx, BytesSent: tx, ConnectedSince: connectedSince}
+	}
+	tests := []struct {
+		name      string
+		snapshots [][]ovpnstats.ClientInfo
+		want      ovpnstats.Traffic
+	}{
+		{
+			name:      "growing session",
+			snapshots: [][]ovpnstats.ClientInfo{{client(100, 10, since)}, {client(150, 30, since)}},
+			want:      ovpnstats.Traffic{Rx: 150, Tx: 30},
+		},
+		{
+			name:      "reconnection",
+			snapshots: [][]ovpnstats.ClientInfo{{client(100, 10, since)}, {client(40, 5, since.Add(time.Hour))}},
+			want:      ovpnstats.Traffic{Rx: 140, Tx: 15},
+		},
+		{
+			name:      "session missing from a snapshot",
+			snapshots: [][]ovpnstats.ClientInfo{{client(100, 10, since)}, nil, {client(150, 30, since)}},
+			want:      ovpnstats.Traffic{Rx: 150, Tx: 30},
+		},
+		{
+			name:      "counter reset",
+			snapshots: [][]ovpnstats.ClientInfo{{client(100, 10, since)}, {client(20, 2, since)}},
+			want:      ovpnstats.Traffic{Rx: 120, Tx: 12},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var traffic ovpnstats.AccumulatedTraffic
+			for _, clients := range test.snapshots {
+				traffic.Update(&ovpnstats.Status{Clients: clients})
+			}
+			if got := traffic.ByCommonName()["alice"]; got != test.want {
+				t.Errorf("ByCommonName()[alice] = %+v, want %+v", got, test.want)
+			}
+			if got := traffic.ByUsername()["alice"]; got != test.want {
+				t.Errorf("ByUsername()[alice] = %+v, want %+v", got, test.want)
+			}
+			traffic.Reset()
+			if got := traffic.ByCommonName(); len(got) != 0 {
+				t.Errorf("ByCommonName() after Reset = %+v, want empty", got)
+			}
+		})
+	}
+}
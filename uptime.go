@@ -0,0 +1,64 @@
+package ovpnstats
+
+import "time"
+
+// ConnectedDuration returns how long the client has been connected at `now`, or 0 when Connected Since is unknown
+func (c ClientInfo) ConnectedDuration(now time.Time) time.Duration {
+	if !c.HasConnectedSince() {
+		return 0
+	}
+	return now.Sub(c.ConnectedSince)
+}
+
+// HasConnectedSince reports whether the client's Connected Since is known, i.e. not the zero time.Time.
+// It's unknown when the status has no such column or, with WithNonPositiveTimeUnknown, an invalid one
+func (c ClientInfo) HasConnectedSince() bool {
+	return !c.ConnectedSince.IsZero()
+}
+
+// HasLastRef reports whether the route's Last Ref is known, i.e. not the zero time.Time
+func (r RoutingInfo) HasLastRef() bool {
+	return !r.LastRef.IsZero()
+}
+
+// LongLivedClients returns the clients which have been connected for at least `min` at `now`,
+// e.g. the candidates to be asked for a reconnection before a maintenance window.
+// Clients whose Connected Since is unknown are never returned, even when `min` isn't positive
+func (s *Status) LongLivedClients(min time.Duration, now time.Time) []ClientInfo {
+	var clients []ClientInfo
+	for _, client := range s.Clients {
+		if client.HasConnectedSince() && client.ConnectedDuration(now) >= min {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
+// ClientsConnectedFor returns the clients which have been connected for at least `min` at s.Now(),
+// like LongLivedClients does at the time given to it
+func (s *Status) ClientsConnectedFor(min time.Duration) []ClientInfo {
+	return s.LongLivedClients(min, s.Now())
+}
+
+// ConnectedDuration returns how long `c` has been connected at s.Now(), or 0 when its Connected Since is unknown
+func (s *Status) ConnectedDuration(c ClientInfo) time.Duration {
+	return c.ConnectedDuration(s.Now())
+}
+
+// ConnectedSinceUnix returns ConnectedSince as seconds since the epoch, like the "Connected Since (time_t)" column,
+// or 0 when it's unknown
+func (c ClientInfo) ConnectedSinceUnix() int64 {
+	return unixOrZero(c.ConnectedSince)
+}
+
+// LastRefUnix returns LastRef as seconds since the epoch, like the "Last Ref (time_t)" column, or 0 when it's unknown
+func (r RoutingInfo) LastRefUnix() int64 {
+	return unixOrZero(r.LastRef)
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
@@ -0,0 +1,75 @@
+package ovpnstats_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/emibcn/ovpnstats"
+)
+
+// fixedClock is a Clock always returning the same time
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+func TestStatusClock(t *testing.T) {
+	// statusV2 was written at 10:00, alice connected at 09:00 and bob at 09:30
+	now := time.Unix(1614592800, 0)
+	status := mustParse(t, statusV2, ovpnstats.WithClock(fixedClock(now)))
+	tests := []struct {
+		min       time.Duration
+		wantNames []string
+	}{
+		{min: 0, wantNames: []string{"alice", "bob"}},
+		{min: 30 * time.Minute, wantNames: []string{"alice", "bob"}},
+		{min: 45 * time.Minute, wantNames: []string{"alice"}},
+		{min: 2 * time.Hour},
+	}
+	for _, test := range tests {
+		t.Run(test.min.String(), func(t *testing.T) {
+			var names []string
+			for _, client := range status.ClientsConnectedFor(test.min) {
+				names = append(names, client.Name)
+			}
+			if !reflect.DeepEqual(names, test.wantNames) {
+				t.Errorf("ClientsConnectedFor(%v) = %v, want %v", test.min, names, test.wantNames)
+			}
+		})
+	}
+	if !status.Now().Equal(now) {
+		t.Errorf("Now() = %v, want %v", status.Now(), now)
+	}
+	if got := status.ConnectedDuration(status.Clients[0]); got != time.Hour {
+		t.Errorf("ConnectedDuration(alice) = %v, want 1h", got)
+	}
+	if got := status.ConnectedDuration(ovpnstats.ClientInfo{Name: "unknown"}); got != 0 {
+		t.Errorf("ConnectedDuration without Connected Since = %v, want 0", got)
+	}
+}
+
+func TestLongLivedClientsUnknownConnectedSince(t *testing.T) {
+	now := time.Unix(1614592800, 0)
+	status := &ovpnstats.Status{Clients: []ovpnstats.ClientInfo{
+		session("alice", 0, 0, 1614589200),
+		{Name: "connecting"},
+		session("bob", 1, 1, 1614592800),
+	}}
+	tests := []struct {
+		min       time.Duration
+		wantNames []string
+	}{
+		{min: -time.Hour, wantNames: []string{"alice", "bob"}},
+		{min: 0, wantNames: []string{"alice", "bob"}},
+		{min: time.Minute, wantNames: []string{"alice"}},
+	}
+	for _, test := range tests {
+		t.Run(test.min.String(), func(t *testing.T) {
+			if got := names(status.LongLivedClients(test.min, now)); !reflect.DeepEqual(got, test.wantNames) {
+				t.Errorf("LongLivedClients(%v) = %v, want %v", test.min, got, test.wantNames)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package ovpnstats
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnsafeField is returned by WriteStatus for a field containing the separator or a line break,
+// which can't be written without corrupting the output. Fields are never escaped, as OpenVPN doesn't escape them either.
+// The free text ending TITLE and GLOBAL_STATS lines may contain the separator, as parsing joins it back
+var ErrUnsafeField = errors.New("ovpnstats: field contains the separator or a line break")
+
+// Section is a set of sections of a status file, combined with |
+type Section uint
+
+const (
+	// SectionClients is the CLIENT_LIST section
+	SectionClients Section = 1 << iota
+	// SectionRoutes is the ROUTING_TABLE section
+	SectionRoutes
+	// SectionGlobalStats are the GLOBAL_STATS lines
+	SectionGlobalStats
+
+	// SectionAll are all the sections
+	SectionAll = SectionClients | SectionRoutes | SectionGlobalStats
+)
+
+// WriteOption configures WriteStatus
+type WriteOption func(*writeOptions)
+
+type writeOptions struct {
+	sections Section
+	metadata bool
+}
+
+// WithSections makes WriteStatus write only `sections`, e.g. WithSections(SectionClients) for a client list alone.
+// TITLE, TIME and END lines are always written
+func WithSections(sections Section) WriteOption {
+	return func(o *writeOptions) {
+		o.sections = sections
+	}
+}
+
+// WithMetadata makes WriteStatus write ClientInfo.Metadata as trailing CLIENT_LIST columns named "meta:" followed by
+// the metadata key, one column per key used by any client, sorted by key. Clients without a key get an empty value,
+// and parsing the output gives back the non empty values only. Without any Metadata, nothing changes
+func WithMetadata() WriteOption {
+	return func(o *writeOptions) {
+		o.metadata = true
+	}
+}
+
+// WriteStatus writes `s` to `w` in the openvpn-status.log format of `s.Version`, so that parsing the output gives back `s`.
+// TITLE and TIME are omitted when empty, while the HEADER lines of the sections written and END are always written
+// like OpenVPN does. All sections are written unless WithSections is given.
+// GLOBAL_STATS are written sorted by name. A "Protocol" column is written after the standard columns when any client has
+// a Protocol, followed by a column for each ClientInfo.Extra key used by any client, sorted by key, in which clients
+// without that key get an empty value. Metadata is only written with WithMetadata, and Warnings never are.
+// Unknown Connected Since and Last Ref, i.e. the zero time.Time, are written as empty columns, which parse back as unknown.
+// Writing stops with an error wrapping ErrUnsafeField when a field contains a line break, or the separator of that
+// version outside the Title and GLOBAL_STATS values
+func WriteStatus(w io.Writer, s *Status, opts ...WriteOption) error {
+	o := &writeOptions{sections: SectionAll}
+	for _, opt := range opts {
+		opt(o)
+	}
+	rw := &recordWriter{w: bufio.NewWriter(w), sep: separator(s.Version)}
+	if s.Title != "" {
+		rw.writeFreeText("TITLE", s.Title)
+	}
+	if !s.UpdatedAt.IsZero() {
+		human, unix := formatTime(s.UpdatedAt)
+		rw.write("TIME", human, unix)
+	}
+
+	if o.sections&SectionClients != 0 {
+		writeClients(rw, clientListColumns(s.Version), s.Clients, o.metadata)
+	}
+	if o.sections&SectionRoutes != 0 {
+		writeRoutes(rw, s.Routes)
+	}
+	if o.sections&SectionGlobalStats != 0 {
+		writeGlobalStats(rw, s.GlobalStats)
+	}
+
+	rw.write("END")
+	if rw.err != nil {
+		return rw.err
+	}
+	return rw.w.Flush()
+}
+
+// writeClients writes the CLIENT_LIST section made of `clients` with the HEADER `columns`, followed by the Protocol and
+// Extra columns they use, and by their Metadata when `metadata` is set
+func writeClients(rw *recordWriter, columns []string, clients []ClientInfo, metadata bool) {
+	protocol := false
+	for _, client := range clients {
+		protocol = protocol || client.Protocol != ""
+	}
+	extraKeys := sortedKeys(clients, func(c ClientInfo) map[string]string { return c.Extra })
+	var keys []string
+	if metadata {
+		keys = sortedKeys(clients, func(c ClientInfo) map[string]string { return c.Metadata })
+	}
+	header := append([]string{"HEADER", "CLIENT_LIST"}, columns...)
+	if protocol {
+		header = append(header, "Protocol")
+	}
+	header = append(header, extraKeys...)
+	for _, key := range keys {
+		header = append(header, metadataColumnPrefix+key)
+	}
+	rw.write(header...)
+	for _, client := range clients {
+		connectedSince, connectedSinceUnix := formatTime(client.ConnectedSince)
+		fields := []string{
+			"CLIENT_LIST",
+			client.Name,
+			client.RealAddress,
+			client.VirtualAddress,
+			client.VirtualV6Address,
+			strconv.FormatInt(client.BytesReceived, 10),
+			strconv.FormatInt(client.BytesSent, 10),
+			connectedSince,
+			connectedSinceUnix,
+			client.Username,
+			strconv.Itoa(client.ClientID),
+			strconv.Itoa(client.PeerID),
+			client.DataChannelCipher,
+		}
+		if protocol {
+			fields = append(fields, client.Protocol)
+		}
+		for _, key := range extraKeys {
+			fields = append(fields, client.Extra[key])
+		}
+		for _, key := range keys {
+			fields = append(fields, client.Metadata[key])
+		}
+		rw.write(fields...)
+	}
+}
+
+// sortedKeys returns the sorted keys of the `values` map of any of `clients`, e.g. of their Metadata
+func sortedKeys(clients []ClientInfo, values func(ClientInfo) map[string]string) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, client := range clients {
+		for key := range values(client) {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeRoutes writes the ROUTING_TABLE section made of `routes`
+func writeRoutes(rw *recordWriter, routes []RoutingInfo) {
+	rw.write(append([]string{"HEADER", "ROUTING_TABLE"}, RoutingTableColumns...)...)
+	for _, route := range routes {
+		lastRef, lastRefUnix := formatTime(route.LastRef)
+		rw.write("ROUTING_TABLE", route.VirtualAddress, route.CommonName, route.RealAddress, lastRef, lastRefUnix)
+	}
+}
+
+// writeGlobalStats writes the GLOBAL_STATS lines of `stats`, sorted by name
+func writeGlobalStats(rw *recordWriter, stats map[string]string) {
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rw.writeFreeText("GLOBAL_STATS", name, stats[name])
+	}
+}
+
+// recordWriter writes status file lines, keeping the first error so it's checked once at the end
+type recordWriter struct {
+	w   *bufio.Writer
+	sep string
+	err error
+}
+
+// write writes a single line made of `fields`
+func (rw *recordWriter) write(fields ...string) {
+	rw.writeLine(fields, false)
+}
+
+// writeFreeText writes a single line made of `fields`, the last of which is free text that may contain the separator,
+// as parsing joins the trailing fields of TITLE and GLOBAL_STATS lines back
+func (rw *recordWriter) writeFreeText(fields ...string) {
+	rw.writeLine(fields, true)
+}
+
+// writeLine writes a single line made of `fields`, allowing the separator in the last one when `freeText` is set
+func (rw *recordWriter) writeLine(fields []string, freeText bool) {
+	if rw.err != nil {
+		return
+	}
+	for i, field := range fields {
+		trailing := freeText && i == len(fields)-1
+		if (!trailing && strings.Contains(field, rw.sep)) || strings.ContainsAny(field, "\r\n") {
+			rw.err = fmt.Errorf("%w: %q", ErrUnsafeField, field)
+			return
+		}
+	}
+	rw.w.WriteString(strings.Join(fields, rw.sep))
+	rw.w.WriteByte('\n')
+}
+
+// formatTime returns the human readable and time_t columns of `t`, both empty when it's unknown
+func formatTime(t time.Time) (human string, unix string) {
+	if t.IsZero() {
+		return "", ""
+	}
+	return t.Format(humanTimeLayout), strconv.FormatInt(t.Unix(), 10)
+}
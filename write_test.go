@@ -0,0 +1,225 @@
+package ovpnstats_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/emibcn/ovpnstats"
+	"github.com/emibcn/ovpnstats/ovpnstatstest"
+)
+
+func TestWriteStatusUnsafeFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int
+		client  ovpnstats.ClientInfo
+		wantErr bool
+	}{
+		{name: "tab in version 3", version: 3, client: ovpnstats.ClientInfo{Name: "ali\tce"}, wantErr: true},
+		{name: "comma in version 2", version: 2, client: ovpnstats.ClientInfo{Name: "ali,ce"}, wantErr: true},
+		{name: "newline in version 3", version: 3, client: ovpnstats.ClientInfo{Name: "ali\nce"}, wantErr: true},
+		{name: "carriage return in version 2", version: 2, client: ovpnstats.ClientInfo{Username: "ali\rce"}, wantErr: true},
+		{name: "comma in version 3", version: 3, client: ovpnstats.ClientInfo{Name: "ali,ce"}},
+		{name: "tab in version 2", version: 2, client: ovpnstats.ClientInfo{Name: "ali\tce"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var b bytes.Buffer
+			status := &ovpnstats.Status{Version: test.version, Clients: []ovpnstats.ClientInfo{test.client}}
+			err := ovpnstats.WriteStatus(&b, status)
+			if test.wantErr {
+				if !errors.Is(err, ovpnstats.ErrUnsafeField) {
+					t.Fatalf("WriteStatus error = %v, want ErrUnsafeField", err)
+				}
+				if b.Len() != 0 {
+					t.Errorf("WriteStatus wrote %q before failing", b.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("WriteStatus: %v", err)
+			}
+			parsed := mustParse(t, b.String())
+			if len(parsed.Clients) != 1 || !parsed.Clients[0].Equal(status.Clients[0]) {
+				t.Errorf("round trip gave %+v, want %+v", parsed.Clients, status.Clients)
+			}
+		})
+	}
+}
+
+func TestWriteStatusFreeTextFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  ovpnstats.Status
+		wantErr bool
+	}{
+		{name: "comma in version 2 title", status: ovpnstats.Status{Version: 2, Title: "OpenVPN 2.6.8 [SSL (OpenSSL)] [LZO, LZ4]"}},
+		{name: "tab in version 3 title", status: ovpnstats.Status{Version: 3, Title: "OpenVPN\t2.6.8"}},
+		{name: "comma in version 2 global stat value", status: ovpnstats.Status{Version: 2, GlobalStats: map[string]string{"Queue": "0,1"}}},
+		{name: "newline in title", status: ovpnstats.Status{Version: 2, Title: "OpenVPN\n2.6.8"}, wantErr: true},
+		{name: "newline in global stat value", status: ovpnstats.Status{Version: 2, GlobalStats: map[string]string{"Queue": "0\n1"}}, wantErr: true},
+		{name: "comma in version 2 global stat name", status: ovpnstats.Status{Version: 2, GlobalStats: map[string]string{"Queue,max": "0"}}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var b bytes.Buffer
+			err := ovpnstats.WriteStatus(&b, &test.status)
+			if test.wantErr {
+				if !errors.Is(err, ovpnstats.ErrUnsafeField) {
+					t.Fatalf("WriteStatus error = %v, want ErrUnsafeField", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("WriteStatus: %v", err)
+			}
+			got := mustParse(t, b.String())
+			if got.Title != test.status.Title || len(got.GlobalStats) != len(test.status.GlobalStats) {
+				t.Fatalf("round trip gave title %q and stats %v, want %q and %v", got.Title, got.GlobalStats, test.status.Title, test.status.GlobalStats)
+			}
+			for name, value := range test.status.GlobalStats {
+				if got.GlobalStats[name] != value {
+					t.Errorf("GlobalStats[%q] = %q, want %q", name, got.GlobalStats[name], value)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteStatusRoundTrip(t *testing.T) {
+	openVPN24, err := os.ReadFile("testdata/status-2.4.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		input     string
+		writeOpts []ovpnstats.WriteOption
+	}{
+		{name: "version 2", input: statusV2},
+		{name: "version 3", input: strings.ReplaceAll(statusV2, ",", "\t")},
+		{
+			name: "metadata",
+			input: strings.NewReplacer(
+				"Data Channel Cipher\n", "Data Channel Cipher,meta:tier\n",
+				"AES-256-GCM\n", "AES-256-GCM,gold\n",
+				"CHACHA20-POLY1305\n", "CHACHA20-POLY1305,\n",
+			).Replace(statusV2),
+			writeOpts: []ovpnstats.WriteOption{ovpnstats.WithMetadata()},
+		},
+		{name: "OpenVPN 2.4", input: string(openVPN24)},
+		{
+			name: "Protocol column",
+			input: strings.NewReplacer(
+				"Data Channel Cipher\n", "Data Channel Cipher,Protocol\n",
+				"AES-256-GCM\n", "AES-256-GCM,UDPv4\n",
+				"CHACHA20-POLY1305\n", "CHACHA20-POLY1305,TCPv6_SERVER\n",
+			).Replace(statusV2),
+		},
+		{name: "Protocol prefix", input: strings.ReplaceAll(statusV2, ",203.0.113.5:1194,", ",udp4:203.0.113.5:1194,")},
+		{
+			name: "extra columns",
+			input: strings.NewReplacer(
+				"Data Channel Cipher\n", "Data Channel Cipher,Pool,Remote Port\n",
+				"AES-256-GCM\n", "AES-256-GCM,main,1194\n",
+				"CHACHA20-POLY1305\n", "CHACHA20-POLY1305,,50123\n",
+			).Replace(statusV2),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			want := mustParse(t, test.input)
+			var b bytes.Buffer
+			if err := ovpnstats.WriteStatus(&b, want, test.writeOpts...); err != nil {
+				t.Fatalf("WriteStatus: %v", err)
+			}
+			got := mustParse(t, b.String())
+			ovpnstatstest.AssertStatusEqual(t, want, got)
+		})
+	}
+}
+
+func TestWriteStatusUnknownTimes(t *testing.T) {
+	want := &ovpnstats.Status{
+		Version: 2,
+		Clients: []ovpnstats.ClientInfo{{Name: "alice", RealAddress: "203.0.113.5:1194"}},
+		Routes:  []ovpnstats.RoutingInfo{{VirtualAddress: "10.8.0.2", CommonName: "alice", RealAddress: "203.0.113.5:1194"}},
+	}
+	if got := want.Clients[0].ConnectedSinceUnix(); got != 0 {
+		t.Errorf("ConnectedSinceUnix() = %d, want 0", got)
+	}
+	if got := want.Routes[0].LastRefUnix(); got != 0 {
+		t.Errorf("LastRefUnix() = %d, want 0", got)
+	}
+	var b bytes.Buffer
+	if err := ovpnstats.WriteStatus(&b, want); err != nil {
+		t.Fatalf("WriteStatus: %v", err)
+	}
+	for _, line := range []string{"CLIENT_LIST,alice,203.0.113.5:1194,,,0,0,,,,0,0,\n", "ROUTING_TABLE,10.8.0.2,alice,203.0.113.5:1194,,\n"} {
+		if !strings.Contains(b.String(), line) {
+			t.Errorf("WriteStatus output %q lacks %q", b.String(), line)
+		}
+	}
+	got := mustParse(t, b.String())
+	ovpnstatstest.AssertStatusEqual(t, want, got)
+	if got.Clients[0].HasConnectedSince() || got.Routes[0].HasLastRef() {
+		t.Errorf("unknown times parsed back as %v and %v", got.Clients[0].ConnectedSince, got.Routes[0].LastRef)
+	}
+}
+
+func TestWriteStatusWithSections(t *testing.T) {
+	status := mustParse(t, statusV2)
+	tests := []struct {
+		name      string
+		sections  ovpnstats.Section
+		want      []string
+		wantLines int
+	}{
+		{name: "all", sections: ovpnstats.SectionAll, want: []string{"HEADER,CLIENT_LIST", "CLIENT_LIST", "HEADER,ROUTING_TABLE", "ROUTING_TABLE", "GLOBAL_STATS"}},
+		{name: "clients", sections: ovpnstats.SectionClients, want: []string{"HEADER,CLIENT_LIST", "CLIENT_LIST"}},
+		{name: "routes", sections: ovpnstats.SectionRoutes, want: []string{"HEADER,ROUTING_TABLE", "ROUTING_TABLE"}},
+		{name: "global stats", sections: ovpnstats.SectionGlobalStats, want: []string{"GLOBAL_STATS"}},
+		{name: "clients and routes", sections: ovpnstats.SectionClients | ovpnstats.SectionRoutes, want: []string{"HEADER,CLIENT_LIST", "CLIENT_LIST", "HEADER,ROUTING_TABLE", "ROUTING_TABLE"}},
+		{name: "none", sections: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := ovpnstats.WriteStatus(&b, status, ovpnstats.WithSections(test.sections)); err != nil {
+				t.Fatalf("WriteStatus: %v", err)
+			}
+			lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+			if len(lines) < 3 || !strings.HasPrefix(lines[0], "TITLE,") || !strings.HasPrefix(lines[1], "TIME,") || lines[len(lines)-1] != "END" {
+				t.Fatalf("WriteStatus output %q lacks the TITLE, TIME or END lines", b.String())
+			}
+			var got []string
+			for _, line := range lines[2 : len(lines)-1] {
+				record := strings.SplitN(line, ",", 3)
+				if record[0] == "HEADER" {
+					got = append(got, record[0]+","+record[1])
+				} else if len(got) == 0 || got[len(got)-1] != record[0] {
+					got = append(got, record[0])
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("WithSections(%v) wrote %v, want %v", test.sections, got, test.want)
+			}
+
+			want := status.Clone()
+			if test.sections&ovpnstats.SectionClients == 0 {
+				want.Clients = nil
+			}
+			if test.sections&ovpnstats.SectionRoutes == 0 {
+				want.Routes = nil
+			}
+			if test.sections&ovpnstats.SectionGlobalStats == 0 {
+				want.GlobalStats = nil
+			}
+			ovpnstatstest.AssertStatusEqual(t, want, mustParse(t, b.String()))
+		})
+	}
+}